@@ -0,0 +1,10 @@
+-- sample tags and features of tenant 1 for local development, never applied by
+-- migrations: make seed
+
+INSERT INTO tags (tenant_id, name)
+SELECT 1, name FROM (VALUES ('Tag1'), ('Tag2'), ('Tag3'), ('Tag4')) AS seed(name)
+WHERE NOT EXISTS (SELECT 1 FROM tags WHERE tenant_id = 1);
+
+INSERT INTO features (tenant_id, name)
+SELECT 1, name FROM (VALUES ('Feature1'), ('Feature2'), ('Feature3'), ('Feature4')) AS seed(name)
+WHERE NOT EXISTS (SELECT 1 FROM features WHERE tenant_id = 1);
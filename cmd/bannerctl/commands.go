@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/banner"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity/dto"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/feature"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/tag"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+	"github.com/go-playground/validator/v10"
+)
+
+var errUsage = errors.New("unknown command, run bannerctl -h")
+
+type cli struct {
+	ctx       context.Context
+	principal *auth.Principal
+	out       *printer
+	banners   banner.Usecase
+	tags      tag.Usecase
+	features  feature.Usecase
+}
+
+func (c *cli) run(resource, action string, args []string) error {
+	switch resource {
+	case "banner":
+		return c.banner(action, args)
+	case "tag":
+		return c.tag(action, args)
+	case "feature":
+		return c.feature(action, args)
+	}
+	return errUsage
+}
+
+func (c *cli) banner(action string, args []string) error {
+	fs := flag.NewFlagSet("banner "+action, flag.ContinueOnError)
+	id := fs.Int("id", 0, "banner id")
+	file := fs.String("file", "", "JSON file with the banner, - reads stdin")
+	tagId := fs.Int("tag", 0, "filter by tag id")
+	featureId := fs.Int("feature", 0, "filter by feature id")
+	limit := fs.Int("limit", 100, "page size")
+	offset := fs.Int("offset", 0, "page offset")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch action {
+	case "list":
+		banners, err := c.banners.GetBanners(c.ctx, c.principal, *tagId, *featureId, *limit, *offset)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(banners))
+		for _, b := range banners {
+			content, _ := json.Marshal(b.Content)
+			rows = append(rows, []string{
+				strconv.Itoa(b.BannerId), strconv.Itoa(b.FeatureId), joinInts(b.TagsId),
+				strconv.FormatBool(b.IsActive != nil && *b.IsActive), b.UpdateDate.Format("2006-01-02 15:04:05"), string(content),
+			})
+		}
+		return c.out.print(dto.BannerToArrayResponseDTO(banners), []string{"ID", "FEATURE", "TAGS", "ACTIVE", "UPDATED", "CONTENT"}, rows)
+	case "create":
+		var bannerDTO dto.BannerCreateRequestDTO
+		if err := readJSON(*file, &bannerDTO); err != nil {
+			return err
+		}
+		if err := validator.New().Struct(bannerDTO); err != nil {
+			return err
+		}
+
+		b := dto.BannerCreateDToToBanner(bannerDTO)
+		bannerId, err := c.banners.CreateBanner(c.ctx, c.principal, &b)
+		if err != nil {
+			return describe(err)
+		}
+		return c.out.print(map[string]int{"banner_id": bannerId}, []string{"ID"}, [][]string{{strconv.Itoa(bannerId)}})
+	case "update":
+		if *id == 0 {
+			return errors.New("-id is required")
+		}
+
+		var bannerDTO dto.BannerUpdateRequestDTO
+		if err := readJSON(*file, &bannerDTO); err != nil {
+			return err
+		}
+
+		b := dto.BannerUpdateDToToBanner(bannerDTO, *id)
+		if err := c.banners.UpdateBanner(c.ctx, c.principal, &b); err != nil {
+			return describe(err)
+		}
+		return c.out.done("updated banner %d", *id)
+	case "activate", "deactivate":
+		if *id == 0 {
+			return errors.New("-id is required")
+		}
+
+		active := action == "activate"
+		b := entity.Banner{BannerId: *id, IsActive: &active}
+		if err := c.banners.UpdateBanner(c.ctx, c.principal, &b); err != nil {
+			return describe(err)
+		}
+		return c.out.done("%sd banner %d", action, *id)
+	case "delete":
+		if *id == 0 {
+			return errors.New("-id is required")
+		}
+
+		if err := c.banners.DeleteBanner(c.ctx, c.principal, *id); err != nil {
+			return describe(err)
+		}
+		return c.out.done("deleted banner %d", *id)
+	}
+	return errUsage
+}
+
+func (c *cli) tag(action string, args []string) error {
+	fs := flag.NewFlagSet("tag "+action, flag.ContinueOnError)
+	id := fs.Int("id", 0, "tag id")
+	name := fs.String("name", "", "tag name")
+	limit := fs.Int("limit", 100, "page size")
+	offset := fs.Int("offset", 0, "page offset")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	tenantId := c.principal.TenantId
+	switch action {
+	case "list":
+		tags, err := c.tags.GetTags(tenantId, *limit, *offset)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(tags))
+		for _, t := range tags {
+			rows = append(rows, []string{strconv.Itoa(t.TagId), t.Name})
+		}
+		return c.out.print(dto.TagToArrayResponseDTO(tags), []string{"ID", "NAME"}, rows)
+	case "create":
+		if *name == "" {
+			return errors.New("-name is required")
+		}
+
+		tagId, err := c.tags.CreateTag(tenantId, &entity.Tag{Name: *name})
+		if err != nil {
+			return err
+		}
+		return c.out.print(map[string]int{"tag_id": tagId}, []string{"ID"}, [][]string{{strconv.Itoa(tagId)}})
+	case "update":
+		if *id == 0 || *name == "" {
+			return errors.New("-id and -name are required")
+		}
+
+		if err := c.tags.UpdateTag(tenantId, &entity.Tag{TagId: *id, Name: *name}); err != nil {
+			return describe(err)
+		}
+		return c.out.done("updated tag %d", *id)
+	case "delete":
+		if *id == 0 {
+			return errors.New("-id is required")
+		}
+
+		if err := c.tags.DeleteTag(tenantId, *id); err != nil {
+			return describe(err)
+		}
+		return c.out.done("deleted tag %d", *id)
+	}
+	return errUsage
+}
+
+func (c *cli) feature(action string, args []string) error {
+	fs := flag.NewFlagSet("feature "+action, flag.ContinueOnError)
+	id := fs.Int("id", 0, "feature id")
+	name := fs.String("name", "", "feature name")
+	limit := fs.Int("limit", 100, "page size")
+	offset := fs.Int("offset", 0, "page offset")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	tenantId := c.principal.TenantId
+	switch action {
+	case "list":
+		features, err := c.features.GetFeatures(tenantId, *limit, *offset)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(features))
+		for _, f := range features {
+			rows = append(rows, []string{strconv.Itoa(f.FeatureId), f.Name})
+		}
+		return c.out.print(dto.FeatureToArrayResponseDTO(features), []string{"ID", "NAME"}, rows)
+	case "create":
+		if *name == "" {
+			return errors.New("-name is required")
+		}
+
+		featureId, err := c.features.CreateFeature(tenantId, &entity.Feature{Name: *name})
+		if err != nil {
+			return err
+		}
+		return c.out.print(map[string]int{"feature_id": featureId}, []string{"ID"}, [][]string{{strconv.Itoa(featureId)}})
+	case "update":
+		if *id == 0 || *name == "" {
+			return errors.New("-id and -name are required")
+		}
+
+		if err := c.features.UpdateFeature(tenantId, &entity.Feature{FeatureId: *id, Name: *name}); err != nil {
+			return describe(err)
+		}
+		return c.out.done("updated feature %d", *id)
+	case "delete":
+		if *id == 0 {
+			return errors.New("-id is required")
+		}
+
+		if err := c.features.DeleteFeature(tenantId, *id); err != nil {
+			return describe(err)
+		}
+		return c.out.done("deleted feature %d", *id)
+	}
+	return errUsage
+}
+
+// describe turns the errors the HTTP layer maps to status codes into messages
+// an operator can act on.
+func describe(err error) error {
+	var conflict *entity.BannerConflictError
+	var validation *entity.ContentValidationError
+	switch {
+	case errors.As(err, &conflict):
+		return fmt.Errorf("conflicts with banners %s: %w", joinInts(conflict.BannerIds), err)
+	case errors.As(err, &validation):
+		details := make([]string, 0, len(validation.Violations))
+		for _, violation := range validation.Violations {
+			details = append(details, fmt.Sprintf("\n  %s: %s", violation.Path, violation.Message))
+		}
+		return fmt.Errorf("%w%s", err, strings.Join(details, ""))
+	case errors.Is(err, entity.ErrorsForbidden):
+		return fmt.Errorf("role lacks the permission, try -role admin: %w", err)
+	case errors.Is(err, entity.ErrorsNotFound):
+		return fmt.Errorf("not found: %w", err)
+	case errors.Is(err, entity.ErrorsConflict):
+		return fmt.Errorf("still in use: %w", err)
+	}
+	return err
+}
+
+func readJSON(path string, v interface{}) error {
+	if path == "" {
+		return errors.New("-file is required")
+	}
+
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		f, err = os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func joinInts(ids []int) string {
+	s := make([]string, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, strconv.Itoa(id))
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,130 @@
+// bannerctl runs banner, tag and feature operations against the production
+// stores through the same usecases as the HTTP API.
+//
+//	bannerctl [-tenant 1] [-role admin] [-output table|json] <banner|tag|feature> <action> [flags]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"syscall"
+
+	"github.com/DmitriyKomarovCoder/banner-api/config"
+	repositoryBanner "github.com/DmitriyKomarovCoder/banner-api/internal/banner/repository"
+	usecaseBanner "github.com/DmitriyKomarovCoder/banner-api/internal/banner/usecase"
+	repositoryExperiment "github.com/DmitriyKomarovCoder/banner-api/internal/experiment/repository"
+	usecaseExperiment "github.com/DmitriyKomarovCoder/banner-api/internal/experiment/usecase"
+	repositoryFeature "github.com/DmitriyKomarovCoder/banner-api/internal/feature/repository"
+	usecaseFeature "github.com/DmitriyKomarovCoder/banner-api/internal/feature/usecase"
+	repositoryTag "github.com/DmitriyKomarovCoder/banner-api/internal/tag/repository"
+	usecaseTag "github.com/DmitriyKomarovCoder/banner-api/internal/tag/usecase"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/metrics"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/postgres"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/redis"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+)
+
+const usage = `usage: bannerctl [flags] <resource> <action> [action flags]
+
+resources and actions:
+  banner   list [-tag N] [-feature N] [-limit N] [-offset N]
+           create -file banner.json
+           update -id N -file patch.json
+           delete -id N
+           activate -id N
+           deactivate -id N
+  tag      list [-limit N] [-offset N] | create -name S | update -id N -name S | delete -id N
+  feature  list [-limit N] [-offset N] | create -name S | update -id N -name S | delete -id N
+
+flags:
+`
+
+func main() {
+	fs := flag.NewFlagSet("bannerctl", flag.ExitOnError)
+	configPath := fs.String("config", "config/config.yaml", "path to config.yaml")
+	tenant := fs.Int("tenant", 1, "tenant to operate on")
+	role := fs.String("role", "admin", "role from config.yaml the command runs with")
+	output := fs.String("output", outputTable, "output format: table or json")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(os.Args[1:])
+
+	if fs.NArg() < 2 || *tenant <= 0 || (*output != outputTable && *output != outputJSON) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		fatalf("Failed to load .env file: %v", err)
+	}
+
+	cfg, err := config.NewConfig(*configPath)
+	if err != nil {
+		fatalf("Config error %s", err)
+	}
+
+	policy, err := auth.NewPolicy(cfg.Roles)
+	if err != nil {
+		fatalf("auth.NewPolicy: %v", err)
+	}
+
+	principal := policy.Principal(&auth.Claims{
+		Role:             *role,
+		TenantId:         *tenant,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "bannerctl:" + operator()},
+	})
+
+	pg, err := postgres.New(cfg.PG.URL, cfg.PG.PoolMax)
+	if err != nil {
+		fatalf("postgres.New: %v", err)
+	}
+	defer pg.Close(context.Background())
+
+	rd := redis.NewRedisRepository(cfg.Redis.Address, cfg.Redis.DB, logger.Logger{Logger: logrus.New()})
+	if err := rd.Connect(); err != nil {
+		fatalf("redis connect: %v", err)
+	}
+	defer rd.Close(context.Background())
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cache := repositoryBanner.NewCache(rd.Client, cfg.Redis.Timeout, metrics.New())
+	experiments := usecaseExperiment.NewUsecase(repositoryExperiment.NewRepository(pg.Pool, cfg.PG.QueryTimeout),
+		repositoryExperiment.NewCounter(rd.Client, cfg.Redis.Timeout), cfg.Experiments.CacheTTL)
+	c := &cli{
+		ctx:       ctx,
+		principal: principal,
+		out:       newPrinter(os.Stdout, *output),
+		banners:   usecaseBanner.NewUsecase(repositoryBanner.NewRepository(pg.Pool, cfg.Banner.VersionsRetention, cfg.PG.QueryTimeout), cache, experiments),
+		tags:      usecaseTag.NewUsecase(repositoryTag.NewRepository(pg.Pool)),
+		features:  usecaseFeature.NewUsecase(repositoryFeature.NewRepository(pg.Pool)),
+	}
+
+	if err := c.run(fs.Arg(0), fs.Arg(1), fs.Args()[2:]); err != nil {
+		fatalf("%s %s: %v", fs.Arg(0), fs.Arg(1), err)
+	}
+}
+
+// operator names the person behind the change in the banner history.
+func operator() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
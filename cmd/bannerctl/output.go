@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) *printer {
+	return &printer{w: w, format: format}
+}
+
+// print writes v as JSON, or header and rows as an aligned table.
+func (p *printer) print(v interface{}, header []string, rows [][]string) error {
+	if p.format == outputJSON {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// done reports a command without a result.
+func (p *printer) done(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if p.format == outputJSON {
+		return json.NewEncoder(p.w).Encode(map[string]string{"result": msg})
+	}
+
+	_, err := fmt.Fprintln(p.w, msg)
+	return err
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/DmitriyKomarovCoder/banner-api/config"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/migrations"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/migrate"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/postgres"
+	"github.com/joho/godotenv"
+)
+
+const (
+	path  = "config/config.yaml"
+	usage = "usage: migrate up|down|status"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal(usage)
+	}
+
+	err := godotenv.Load()
+	if err != nil {
+		fmt.Println("Failed to load .env file")
+		return
+	}
+
+	cfg, err := config.NewConfig(path)
+	if err != nil {
+		log.Fatalf("Config error %s", err)
+	}
+
+	pg, err := postgres.New(cfg.PG.URL, cfg.PG.PoolMax)
+	if err != nil {
+		log.Fatalf("postgres.New: %s", err)
+	}
+	defer pg.Close(context.Background())
+
+	migrator, err := migrate.New(pg.Pool, migrations.FS)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %06d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		m, err := migrator.Down(ctx)
+		if err != nil {
+			if errors.Is(err, migrate.ErrNoMigration) {
+				fmt.Println(err)
+				return
+			}
+			log.Fatal(err)
+		}
+		fmt.Printf("rolled back %06d_%s\n", m.Version, m.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%06d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+	default:
+		log.Fatal(usage)
+	}
+}
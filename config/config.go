@@ -10,9 +10,21 @@ import (
 
 type Config struct {
 	Http            http          `yaml:"http"`
+	Admin           admin         `yaml:"admin"`
 	Log             logCustom     `yaml:"log_file"`
 	PG              postgres      `yaml:"postgres"`
 	Redis           redis         `yaml:"redis"`
+	JWT             jwt           `yaml:"jwt"`
+	Roles           roles         `yaml:"roles"`
+	APIKeys         apiKeys       `yaml:"api_keys"`
+	Banner          banner        `yaml:"banner"`
+	Experiments     experiments   `yaml:"experiments"`
+	Events          events        `yaml:"events"`
+	Deletion        deletion      `yaml:"deletion"`
+	Webhooks        webhooks      `yaml:"webhooks"`
+	RateLimit       rateLimit     `yaml:"rate_limit"`
+	Idempotency     idempotency   `yaml:"idempotency"`
+	Tracing         tracing       `yaml:"tracing"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
 }
 
@@ -23,6 +35,12 @@ type http struct {
 	WriteTimeout time.Duration `yaml:"writeTimeout"`
 }
 
+// admin is the internal listener serving /metrics, kept off the public port
+type admin struct {
+	Host string `yaml:"host"`
+	Port string `yaml:"port"`
+}
+
 type logCustom struct {
 	Path string `yaml:"path"`
 }
@@ -35,11 +53,116 @@ type postgres struct {
 	Host     string `env:"DB_HOST"`
 	PoolMax  int32  `yaml:"pool_max"`
 	URL      string
+	// QueryTimeout bounds a single repository call
+	QueryTimeout time.Duration `yaml:"query_timeout"`
 }
 
 type redis struct {
-	Address string `yaml:"host"`
-	DB      int    `yaml:"db"`
+	Address string        `yaml:"host"`
+	DB      int           `yaml:"db"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type jwt struct {
+	Algorithm     string `yaml:"algorithm"`
+	Secret        string `env:"JWT_SECRET"`
+	PublicKeyPath string `yaml:"public_key_path"`
+}
+
+// roles maps a role name to the permissions it grants
+type roles map[string][]string
+
+type apiKeys struct {
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+type banner struct {
+	VersionsRetention int `yaml:"versions_retention"`
+}
+
+// experiments.cache_ttl is how long a replica keeps serving a stopped experiment
+type experiments struct {
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// events configures the impression recorder: impressions are written once
+// batch_size are buffered or every flush_interval, beyond buffer_size they are dropped
+type events struct {
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	BatchSize     int           `yaml:"batch_size"`
+	BufferSize    int           `yaml:"buffer_size"`
+}
+
+// deletion configures the bulk deletion worker, a job whose batch fails is
+// retried like a webhook delivery and is dead after max_attempts failures in a row
+type deletion struct {
+	Interval    time.Duration `yaml:"interval"`
+	BatchSize   int           `yaml:"batch_size"`
+	MaxAttempts int           `yaml:"max_attempts"`
+	BackoffBase time.Duration `yaml:"backoff_base"`
+	BackoffMax  time.Duration `yaml:"backoff_max"`
+}
+
+// webhooks configures the delivery worker: a failed delivery is retried after
+// backoff_base, doubled on every attempt up to backoff_max, and is dead after max_attempts.
+// allow_insecure lets endpoints use http and private addresses, for local development only
+type webhooks struct {
+	Interval      time.Duration `yaml:"interval"`
+	BatchSize     int           `yaml:"batch_size"`
+	Timeout       time.Duration `yaml:"timeout"`
+	MaxAttempts   int           `yaml:"max_attempts"`
+	BackoffBase   time.Duration `yaml:"backoff_base"`
+	BackoffMax    time.Duration `yaml:"backoff_max"`
+	AllowInsecure bool          `yaml:"allow_insecure"`
+}
+
+// rateLimit holds the per caller quotas, routes are keyed by the route template,
+// optionally prefixed with the method ("GET /api/v1/user_banner"); a zero limit disables it.
+// per_ip bounds every remote address before authentication, across all routes
+type rateLimit struct {
+	Default quota            `yaml:"default"`
+	Routes  map[string]quota `yaml:"routes"`
+	PerIP   quota            `yaml:"per_ip"`
+}
+
+// quota lets limit requests through per period with bursts of up to burst (defaults to limit)
+type quota struct {
+	Limit  int           `yaml:"limit"`
+	Period time.Duration `yaml:"period"`
+	Burst  int           `yaml:"burst"`
+}
+
+// idempotency.ttl is how long a response is replayed for its Idempotency-Key
+type idempotency struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// tracing selects the span exporter: none, stdout for local runs or otlp (http)
+type tracing struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+const redacted = "[REDACTED]"
+
+// String keeps secrets out of the logs, the configuration is printed on start.
+func (c Config) String() string {
+	// plain drops the String method, so Sprint doesn't come back here
+	type plain Config
+	p := plain(c)
+	if p.PG.Password != "" {
+		p.PG.Password = redacted
+	}
+	if p.PG.URL != "" {
+		p.PG.URL = redacted
+	}
+	if p.JWT.Secret != "" {
+		p.JWT.Secret = redacted
+	}
+	return fmt.Sprint(p)
 }
 
 func NewConfig(path string) (*Config, error) {
@@ -0,0 +1,22 @@
+package apikey
+
+import (
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+)
+
+type Usecase interface {
+	auth.KeyAuthenticator
+	GetKeys(tenantId, limit, offset int) ([]entity.APIKey, error)
+	IssueKey(principal *auth.Principal, key *entity.APIKey) (int, string, error)
+	RotateKey(tenantId, keyId int) (string, error)
+	RevokeKey(tenantId, keyId int) error
+}
+
+type Repository interface {
+	GetKeys(tenantId, limit, offset int) ([]entity.APIKey, error)
+	GetKeyByHash(hash string) (*entity.APIKey, error)
+	CreateKey(tenantId int, key *entity.APIKey, hash string) (int, error)
+	UpdateKeyHash(tenantId, keyId int, hash string) error
+	RevokeKey(tenantId, keyId int) error
+}
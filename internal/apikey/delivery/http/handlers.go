@@ -0,0 +1,126 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/apikey"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity/dto"
+	util "github.com/DmitriyKomarovCoder/banner-api/internal/utils/http"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	keyIdPath = "id"
+)
+
+type Handler struct {
+	usecase apikey.Usecase
+	log     logger.Logger
+}
+
+func NewHandler(usecase apikey.Usecase, log logger.Logger) *Handler {
+	return &Handler{
+		usecase: usecase,
+		log:     log,
+	}
+}
+
+func (h *Handler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := util.GetLimitOffset(r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+		return
+	}
+
+	keys, err := h.usecase.GetKeys(util.GetPrincipal(r).TenantId, limit, offset)
+	if err != nil {
+		h.log.FromContext(r.Context()).Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.APIKeyToArrayResponseDTO(keys))
+}
+
+func (h *Handler) IssueKey(w http.ResponseWriter, r *http.Request) {
+	var keyDTO dto.APIKeyCreateRequestDTO
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&keyDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(keyDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	key := dto.APIKeyCreateDTOToAPIKey(keyDTO)
+	keyId, plain, err := h.usecase.IssueKey(util.GetPrincipal(r), &key)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotBody) {
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+			return
+		} else if errors.Is(err, entity.ErrorsForbidden) {
+			util.ErrorResponse(w, http.StatusForbidden, err, "", h.log.FromContext(r.Context()))
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusCreated, dto.APIKeyIssuedResponseDTO{KeyId: keyId, Key: plain})
+}
+
+func (h *Handler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(keyIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	plain, err := h.usecase.RotateKey(util.GetPrincipal(r).TenantId, id)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.APIKeyIssuedResponseDTO{KeyId: id, Key: plain})
+}
+
+func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(keyIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	err = h.usecase.RevokeKey(util.GetPrincipal(r).TenantId, id)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	getKeysMSG       = "GetKeys repository layer: %w"
+	getKeyByHashMSG  = "GetKeyByHash repository layer: %w"
+	createKeyMSG     = "CreateKey repository layer: %w"
+	updateKeyHashMSG = "UpdateKeyHash repository layer: %w"
+	revokeKeyMSG     = "RevokeKey repository layer: %w"
+	// =============================
+	getKeysSQL = `SELECT key_id, tenant_id, name, scopes, expires_at, revoked_at, created_at
+				  FROM api_keys
+				  WHERE tenant_id = $1
+				  ORDER BY key_id
+				  LIMIT $2 OFFSET $3;`
+
+	// the hash is unique across tenants, the key itself tells which tenant it belongs to
+	getKeyByHashSQL = `SELECT key_id, tenant_id, name, scopes, expires_at, revoked_at, created_at
+					   FROM api_keys
+					   WHERE key_hash = $1;`
+
+	createKeySQL = `INSERT INTO api_keys (tenant_id, name, key_hash, scopes, expires_at)
+					VALUES ($1, $2, $3, $4, $5)
+					RETURNING key_id;`
+
+	updKeyHashSQL = `UPDATE api_keys SET key_hash = $1 WHERE tenant_id = $2 AND key_id = $3 AND revoked_at IS NULL;`
+	revokeKeySQL  = `UPDATE api_keys SET revoked_at = now() WHERE tenant_id = $1 AND key_id = $2 AND revoked_at IS NULL;`
+)
+
+type repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *repository {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) GetKeys(tenantId, limit, offset int) ([]entity.APIKey, error) {
+	rows, err := r.db.Query(context.Background(), getKeysSQL, tenantId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getKeysMSG, err)
+	}
+	defer rows.Close()
+
+	keys := []entity.APIKey{}
+	for rows.Next() {
+		var key entity.APIKey
+		if err := rows.Scan(&key.KeyId, &key.TenantId, &key.Name, &key.Scopes, &key.ExpiresAt, &key.RevokedAt, &key.CreatedDate); err != nil {
+			return nil, fmt.Errorf(getKeysMSG, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(getKeysMSG, err)
+	}
+
+	return keys, nil
+}
+
+func (r *repository) GetKeyByHash(hash string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	err := r.db.QueryRow(context.Background(), getKeyByHashSQL, hash).Scan(
+		&key.KeyId,
+		&key.TenantId,
+		&key.Name,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.RevokedAt,
+		&key.CreatedDate,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(getKeyByHashMSG, entity.ErrorsNotFound)
+		}
+		return nil, fmt.Errorf(getKeyByHashMSG, err)
+	}
+	return &key, nil
+}
+
+func (r *repository) CreateKey(tenantId int, key *entity.APIKey, hash string) (int, error) {
+	var keyId int
+	err := r.db.QueryRow(context.Background(), createKeySQL, tenantId, key.Name, hash, key.Scopes, key.ExpiresAt).Scan(&keyId)
+	if err != nil {
+		return 0, fmt.Errorf(createKeyMSG, err)
+	}
+	return keyId, nil
+}
+
+func (r *repository) UpdateKeyHash(tenantId, keyId int, hash string) error {
+	cmdTag, err := r.db.Exec(context.Background(), updKeyHashSQL, hash, tenantId, keyId)
+	if err != nil {
+		return fmt.Errorf(updateKeyHashMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(updateKeyHashMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
+
+func (r *repository) RevokeKey(tenantId, keyId int) error {
+	cmdTag, err := r.db.Exec(context.Background(), revokeKeySQL, tenantId, keyId)
+	if err != nil {
+		return fmt.Errorf(revokeKeyMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(revokeKeyMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/apikey"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+)
+
+const (
+	keyPrefix = "bk_"
+	keyBytes  = 32
+)
+
+type cachedKey struct {
+	keyId     int
+	principal *auth.Principal
+	expiresAt time.Time
+}
+
+type Usecase struct {
+	keyRepo  apikey.Repository
+	cacheTTL time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cachedKey
+}
+
+// NewUsecase creates api key usecase. Valid keys are kept in memory for cacheTTL,
+// so a revoked key may still be accepted by other replicas for that long.
+func NewUsecase(kr apikey.Repository, cacheTTL time.Duration) *Usecase {
+	return &Usecase{
+		keyRepo:  kr,
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]cachedKey),
+	}
+}
+
+const (
+	getKeysMSG      = "GetKeys usecase layer: %w"
+	issueKeyMSG     = "IssueKey usecase layer: %w"
+	rotateKeyMSG    = "RotateKey usecase layer: %w"
+	revokeKeyMSG    = "RevokeKey usecase layer: %w"
+	authenticateMSG = "Authenticate usecase layer: %w"
+)
+
+func (u *Usecase) GetKeys(tenantId, limit, offset int) ([]entity.APIKey, error) {
+	keys, err := u.keyRepo.GetKeys(tenantId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getKeysMSG, err)
+	}
+	return keys, nil
+}
+
+// IssueKey stores a new key of the principal's tenant and returns its id and
+// plain value. Only the hash is persisted, so the plain value can't be recovered
+// later. A key never gets a scope the principal doesn't hold itself.
+func (u *Usecase) IssueKey(principal *auth.Principal, key *entity.APIKey) (int, string, error) {
+	for _, scope := range key.Scopes {
+		if !auth.IsKnownPermission(auth.Permission(scope)) {
+			return 0, "", fmt.Errorf(issueKeyMSG, entity.ErrorsNotBody)
+		}
+		if !principal.Can(auth.Permission(scope)) {
+			return 0, "", fmt.Errorf(issueKeyMSG, entity.ErrorsForbidden)
+		}
+	}
+
+	plain, err := generateKey()
+	if err != nil {
+		return 0, "", fmt.Errorf(issueKeyMSG, err)
+	}
+
+	keyId, err := u.keyRepo.CreateKey(principal.TenantId, key, hashKey(plain))
+	if err != nil {
+		return 0, "", fmt.Errorf(issueKeyMSG, err)
+	}
+
+	return keyId, plain, nil
+}
+
+func (u *Usecase) RotateKey(tenantId, keyId int) (string, error) {
+	plain, err := generateKey()
+	if err != nil {
+		return "", fmt.Errorf(rotateKeyMSG, err)
+	}
+
+	if err := u.keyRepo.UpdateKeyHash(tenantId, keyId, hashKey(plain)); err != nil {
+		return "", fmt.Errorf(rotateKeyMSG, err)
+	}
+
+	u.forget(keyId)
+	return plain, nil
+}
+
+func (u *Usecase) RevokeKey(tenantId, keyId int) error {
+	if err := u.keyRepo.RevokeKey(tenantId, keyId); err != nil {
+		return fmt.Errorf(revokeKeyMSG, err)
+	}
+
+	u.forget(keyId)
+	return nil
+}
+
+func (u *Usecase) Authenticate(key string) (*auth.Principal, error) {
+	hash := hashKey(key)
+	now := time.Now()
+
+	u.mu.RLock()
+	cached, ok := u.cache[hash]
+	u.mu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.principal, nil
+	}
+
+	apiKey, err := u.keyRepo.GetKeyByHash(hash)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			return nil, auth.ErrKeyInvalid
+		}
+		return nil, fmt.Errorf(authenticateMSG, err)
+	}
+
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt)) {
+		return nil, auth.ErrKeyInvalid
+	}
+
+	principal := &auth.Principal{
+		Subject:     "apikey:" + apiKey.Name,
+		TenantId:    apiKey.TenantId,
+		KeyId:       apiKey.KeyId,
+		Permissions: make(map[auth.Permission]struct{}, len(apiKey.Scopes)),
+	}
+	for _, scope := range apiKey.Scopes {
+		principal.Permissions[auth.Permission(scope)] = struct{}{}
+	}
+
+	expiresAt := now.Add(u.cacheTTL)
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(expiresAt) {
+		expiresAt = *apiKey.ExpiresAt
+	}
+
+	u.mu.Lock()
+	u.cache[hash] = cachedKey{keyId: apiKey.KeyId, principal: principal, expiresAt: expiresAt}
+	u.mu.Unlock()
+
+	return principal, nil
+}
+
+// forget drops cached entries of the key, so this replica stops accepting it at once.
+func (u *Usecase) forget(keyId int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for hash, cached := range u.cache {
+		if cached.keyId == keyId {
+			delete(u.cache, hash)
+		}
+	}
+}
+
+func generateKey() (string, error) {
+	buf := make([]byte, keyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
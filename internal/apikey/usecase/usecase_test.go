@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/apikey"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+)
+
+// fakeRepository only stores keys, the methods a test doesn't override panic
+// on the nil Repository.
+type fakeRepository struct {
+	apikey.Repository
+	created []entity.APIKey
+}
+
+func (f *fakeRepository) CreateKey(tenantId int, key *entity.APIKey, hash string) (int, error) {
+	key.TenantId = tenantId
+	f.created = append(f.created, *key)
+	return len(f.created), nil
+}
+
+func TestUsecase_IssueKey(t *testing.T) {
+	repo := &fakeRepository{}
+	u := NewUsecase(repo, time.Minute)
+	principal := &auth.Principal{
+		Subject:     "apikey:keys",
+		TenantId:    3,
+		Permissions: map[auth.Permission]struct{}{auth.PermAPIKeyManage: {}, auth.PermBannerRead: {}},
+	}
+
+	_, _, err := u.IssueKey(principal, &entity.APIKey{Name: "publisher", Scopes: []string{string(auth.PermBannerPublish)}})
+	if !errors.Is(err, entity.ErrorsForbidden) {
+		t.Errorf("Expected ErrorsForbidden for a scope the principal lacks, got %v", err)
+	}
+
+	_, _, err = u.IssueKey(principal, &entity.APIKey{Name: "unknown", Scopes: []string{"banner:everything"}})
+	if !errors.Is(err, entity.ErrorsNotBody) {
+		t.Errorf("Expected ErrorsNotBody for an unknown scope, got %v", err)
+	}
+
+	if len(repo.created) != 0 {
+		t.Fatalf("Expected no key to be stored, got %v", repo.created)
+	}
+
+	_, plain, err := u.IssueKey(principal, &entity.APIKey{Name: "reader", Scopes: []string{string(auth.PermBannerRead)}})
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if plain == "" || len(repo.created) != 1 || repo.created[0].TenantId != 3 {
+		t.Errorf("Expected a key of tenant 3, got %q %v", plain, repo.created)
+	}
+}
@@ -5,19 +5,50 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os/signal"
 	"syscall"
 
 	"github.com/DmitriyKomarovCoder/banner-api/config"
+	deliveryKey "github.com/DmitriyKomarovCoder/banner-api/internal/apikey/delivery/http"
+	repositoryKey "github.com/DmitriyKomarovCoder/banner-api/internal/apikey/repository"
+	usecaseKey "github.com/DmitriyKomarovCoder/banner-api/internal/apikey/usecase"
 	routerInit "github.com/DmitriyKomarovCoder/banner-api/internal/app/router"
 	deliveryBanner "github.com/DmitriyKomarovCoder/banner-api/internal/banner/delivery/http"
 	repositoryBanner "github.com/DmitriyKomarovCoder/banner-api/internal/banner/repository"
 	usecaseBanner "github.com/DmitriyKomarovCoder/banner-api/internal/banner/usecase"
+	workerBanner "github.com/DmitriyKomarovCoder/banner-api/internal/banner/worker"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	deliveryEvent "github.com/DmitriyKomarovCoder/banner-api/internal/event/delivery/http"
+	repositoryEvent "github.com/DmitriyKomarovCoder/banner-api/internal/event/repository"
+	usecaseEvent "github.com/DmitriyKomarovCoder/banner-api/internal/event/usecase"
+	workerEvent "github.com/DmitriyKomarovCoder/banner-api/internal/event/worker"
+	deliveryExperiment "github.com/DmitriyKomarovCoder/banner-api/internal/experiment/delivery/http"
+	repositoryExperiment "github.com/DmitriyKomarovCoder/banner-api/internal/experiment/repository"
+	usecaseExperiment "github.com/DmitriyKomarovCoder/banner-api/internal/experiment/usecase"
+	deliveryFeature "github.com/DmitriyKomarovCoder/banner-api/internal/feature/delivery/http"
+	repositoryFeature "github.com/DmitriyKomarovCoder/banner-api/internal/feature/repository"
+	usecaseFeature "github.com/DmitriyKomarovCoder/banner-api/internal/feature/usecase"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/migrations"
+	deliveryTag "github.com/DmitriyKomarovCoder/banner-api/internal/tag/delivery/http"
+	repositoryTag "github.com/DmitriyKomarovCoder/banner-api/internal/tag/repository"
+	usecaseTag "github.com/DmitriyKomarovCoder/banner-api/internal/tag/usecase"
+	deliveryWebhook "github.com/DmitriyKomarovCoder/banner-api/internal/webhook/delivery/http"
+	repositoryWebhook "github.com/DmitriyKomarovCoder/banner-api/internal/webhook/repository"
+	usecaseWebhook "github.com/DmitriyKomarovCoder/banner-api/internal/webhook/usecase"
+	workerWebhook "github.com/DmitriyKomarovCoder/banner-api/internal/webhook/worker"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
 	"github.com/DmitriyKomarovCoder/banner-api/pkg/closer"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/idempotency"
 	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/metrics"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/middleware"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/migrate"
 	"github.com/DmitriyKomarovCoder/banner-api/pkg/postgres"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ratelimit"
 	"github.com/DmitriyKomarovCoder/banner-api/pkg/redis"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/tracing"
 )
 
 func Run(cfg *config.Config) {
@@ -29,11 +60,42 @@ func Run(cfg *config.Config) {
 		log.Fatalf("Logger initialisation error %s", err)
 	}
 
+	tr, err := tracing.New(ctx, tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		l.Fatal(fmt.Errorf("error: tracing.New: %w", err))
+	}
+
+	verifier, err := auth.NewVerifier(cfg.JWT.Algorithm, cfg.JWT.Secret, cfg.JWT.PublicKeyPath)
+	if err != nil {
+		l.Fatal(fmt.Errorf("error: auth.NewVerifier: %w", err))
+	}
+
+	policy, err := auth.NewPolicy(cfg.Roles)
+	if err != nil {
+		l.Fatal(fmt.Errorf("error: auth.NewPolicy: %w", err))
+	}
+
 	pg, err := postgres.New(cfg.PG.URL, cfg.PG.PoolMax)
 	if err != nil {
 		l.Fatal(fmt.Errorf("error: postgres.New: %w", err))
 	}
 
+	migrator, err := migrate.New(pg.Pool, migrations.FS)
+	if err != nil {
+		l.Fatal(fmt.Errorf("error: migrate.New: %w", err))
+	}
+
+	// migrations are applied by cmd/migrate, running replicas must not race on them
+	if err := migrator.Check(ctx); err != nil {
+		l.Fatal(fmt.Errorf("error: migrate.Check: %w", err))
+	}
+
 	rd := redis.NewRedisRepository(cfg.Redis.Address, cfg.Redis.DB, *l)
 
 	if err := rd.Connect(); err != nil {
@@ -42,23 +104,115 @@ func Run(cfg *config.Config) {
 	}
 
 	l.Info("Db Connect successfully")
-	cache := repositoryBanner.NewCache(rd.Client)
-	repBanner := repositoryBanner.NewRepository(pg.Pool)
-	useBanner := usecaseBanner.NewUsecase(repBanner, cache)
-	handlerBanner := deliveryBanner.NewHandler(useBanner, *l)
-	router := *routerInit.NewRouter(handlerBanner, l)
+
+	m := metrics.New()
+	if err := m.Register(pg.Collector()); err != nil {
+		l.Fatal(fmt.Errorf("error: metrics.Register: %w", err))
+	}
+
+	repExperiment := repositoryExperiment.NewRepository(pg.Pool, cfg.PG.QueryTimeout)
+	counter := repositoryExperiment.NewCounter(rd.Client, cfg.Redis.Timeout)
+	useExperiment := usecaseExperiment.NewUsecase(repExperiment, counter, cfg.Experiments.CacheTTL)
+	handlerExperiment := deliveryExperiment.NewHandler(useExperiment, *l)
+
+	repEvent := repositoryEvent.NewRepository(pg.Pool, cfg.PG.QueryTimeout)
+	useEvent := usecaseEvent.NewUsecase(repEvent)
+	handlerEvent := deliveryEvent.NewHandler(useEvent, *l)
+	impressionRecorder := workerEvent.NewImpressionRecorder(repEvent, *l, m, cfg.Events.FlushInterval,
+		cfg.Events.BatchSize, cfg.Events.BufferSize)
+
+	cache := repositoryBanner.NewCache(rd.Client, cfg.Redis.Timeout, m)
+	repBanner := repositoryBanner.NewRepository(pg.Pool, cfg.Banner.VersionsRetention, cfg.PG.QueryTimeout)
+	useBanner := usecaseBanner.NewUsecase(repBanner, cache, useExperiment)
+	handlerBanner := deliveryBanner.NewHandler(useBanner, impressionRecorder, repositoryBanner.TTL, *l)
+	deletionWorker := workerBanner.NewDeletionWorker(useBanner, *l, cfg.Deletion.Interval, cfg.Deletion.BatchSize, entity.RetryPolicy{
+		MaxAttempts: cfg.Deletion.MaxAttempts,
+		BackoffBase: cfg.Deletion.BackoffBase,
+		BackoffMax:  cfg.Deletion.BackoffMax,
+	})
+
+	repTag := repositoryTag.NewRepository(pg.Pool)
+	useTag := usecaseTag.NewUsecase(repTag)
+	handlerTag := deliveryTag.NewHandler(useTag, *l)
+
+	repFeature := repositoryFeature.NewRepository(pg.Pool)
+	useFeature := usecaseFeature.NewUsecase(repFeature)
+	handlerFeature := deliveryFeature.NewHandler(useFeature, *l)
+
+	repKey := repositoryKey.NewRepository(pg.Pool)
+	useKey := usecaseKey.NewUsecase(repKey, cfg.APIKeys.CacheTTL)
+	handlerKey := deliveryKey.NewHandler(useKey, *l)
+
+	repWebhook := repositoryWebhook.NewRepository(pg.Pool, cfg.PG.QueryTimeout)
+	sender := repositoryWebhook.NewSender(cfg.Webhooks.Timeout, cfg.Webhooks.AllowInsecure)
+	// a claimed delivery is given back to the queue once the lease runs out,
+	// it has to outlive the request to the endpoint
+	useWebhook := usecaseWebhook.NewUsecase(repWebhook, sender, entity.RetryPolicy{
+		MaxAttempts: cfg.Webhooks.MaxAttempts,
+		BackoffBase: cfg.Webhooks.BackoffBase,
+		BackoffMax:  cfg.Webhooks.BackoffMax,
+	}, 2*cfg.Webhooks.Timeout)
+	handlerWebhook := deliveryWebhook.NewHandler(useWebhook, *l)
+	deliveryWorker := workerWebhook.NewDeliveryWorker(useWebhook, *l, cfg.Webhooks.Interval, cfg.Webhooks.BatchSize)
+
+	limiter := ratelimit.NewLimiter(rd.Client, cfg.Redis.Timeout)
+	quotas := ratelimit.Quotas{
+		Default: ratelimit.Quota(cfg.RateLimit.Default),
+		Routes:  make(map[string]ratelimit.Quota, len(cfg.RateLimit.Routes)),
+		PerIP:   ratelimit.Quota(cfg.RateLimit.PerIP),
+	}
+	for route, quota := range cfg.RateLimit.Routes {
+		quotas.Routes[route] = ratelimit.Quota(quota)
+	}
+
+	// a request can't run longer than the write timeout, its key is free again after that
+	idempotencyStore := idempotency.NewStore(rd.Client, cfg.Redis.Timeout, cfg.Idempotency.TTL, cfg.Http.WriteTimeout)
+
+	router := *routerInit.NewRouter(handlerBanner, handlerTag, handlerFeature, handlerKey, handlerExperiment, handlerEvent, handlerWebhook,
+		verifier, policy, useKey, limiter, quotas, idempotencyStore, m, l)
+
+	// requests still running when shutdown gives up are cancelled through their base context
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
 
 	httpServer := &http.Server{
 		Addr:         cfg.Http.Host + ":" + cfg.Http.Port,
-		Handler:      &router,
+		Handler:      middleware.Timeout(cfg.Http.WriteTimeout)(&router),
+		ReadTimeout:  cfg.Http.ReadTimeout,
+		WriteTimeout: cfg.Http.WriteTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return requestsCtx
+		},
+	}
+
+	adminRouter := http.NewServeMux()
+	adminRouter.Handle("/metrics", m.Handler())
+
+	adminServer := &http.Server{
+		Addr:         cfg.Admin.Host + ":" + cfg.Admin.Port,
+		Handler:      adminRouter,
 		ReadTimeout:  cfg.Http.ReadTimeout,
 		WriteTimeout: cfg.Http.WriteTimeout,
 	}
 
 	c := &closer.Closer{}
 	c.Add(httpServer.Shutdown)
+	c.Add(adminServer.Shutdown)
+	c.Add(func(ctx context.Context) error {
+		cancelRequests()
+		return nil
+	})
+	c.Add(deletionWorker.Close)
+	// impressions left in the buffer are written before postgres goes away
+	c.Add(impressionRecorder.Close)
+	c.Add(deliveryWorker.Close)
 	c.Add(rd.Close)
 	c.Add(pg.Close)
+	c.Add(tr.Close)
+
+	go deletionWorker.Run()
+	go impressionRecorder.Run()
+	go deliveryWorker.Run()
 
 	go func() {
 		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -67,6 +221,13 @@ func Run(cfg *config.Config) {
 	}()
 	l.Infof("server start in port: %v", cfg.Http.Port)
 
+	go func() {
+		if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			l.Fatalf("Erorr starting admin server: %v", err)
+		}
+	}()
+	l.Infof("admin server start in port: %v", cfg.Admin.Port)
+
 	<-ctx.Done()
 	l.Info("shutting down server gracefully")
 
@@ -1,25 +1,104 @@
 package router
 
 import (
+	"net/http"
+
+	apikey "github.com/DmitriyKomarovCoder/banner-api/internal/apikey/delivery/http"
 	banner "github.com/DmitriyKomarovCoder/banner-api/internal/banner/delivery/http"
+	event "github.com/DmitriyKomarovCoder/banner-api/internal/event/delivery/http"
+	experiment "github.com/DmitriyKomarovCoder/banner-api/internal/experiment/delivery/http"
+	feature "github.com/DmitriyKomarovCoder/banner-api/internal/feature/delivery/http"
+	tag "github.com/DmitriyKomarovCoder/banner-api/internal/tag/delivery/http"
+	webhook "github.com/DmitriyKomarovCoder/banner-api/internal/webhook/delivery/http"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
 	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/metrics"
 	"github.com/DmitriyKomarovCoder/banner-api/pkg/middleware"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ratelimit"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
-func NewRouter(hBanner *banner.Handler, logger *logger.Logger) *mux.Router {
+const serviceName = "banner-api"
+
+func NewRouter(hBanner *banner.Handler, hTag *tag.Handler, hFeature *feature.Handler, hKey *apikey.Handler, hExperiment *experiment.Handler,
+	hEvent *event.Handler, hWebhook *webhook.Handler, verifier *auth.Verifier, policy auth.Policy, keys auth.KeyAuthenticator, limiter middleware.RateLimiter, quotas ratelimit.Quotas,
+	idempotency middleware.IdempotencyStore, m *metrics.Metrics, logger *logger.Logger) *mux.Router {
 	r := mux.NewRouter()
 
+	// the span wraps everything else, logging and metrics go next to also see
+	// the 500s written by the panic recovery
+	r.Use(otelmux.Middleware(serviceName))
+	r.Use(middleware.RequestID(logger))
+	r.Use(middleware.AccessLog(logger))
+	r.Use(middleware.Metrics(m))
 	r.Use(middleware.PanicRecovery(logger))
 
 	bannerRouter := r.PathPrefix("/api/v1").Subrouter()
-	bannerRouter.Use(middleware.Auth)
+	// in front of Auth, bad credentials are throttled before they are looked up
+	bannerRouter.Use(middleware.RateLimitIP(limiter, quotas.PerIP, logger))
+	bannerRouter.Use(middleware.Auth(verifier, policy, keys))
+	// after Auth, so that callers are limited by their key or token and not by IP
+	bannerRouter.Use(middleware.RateLimit(limiter, quotas, logger))
+
+	with := func(perm auth.Permission, h http.HandlerFunc) http.Handler {
+		return middleware.RequirePermission(perm)(h)
+	}
+	idempotent := func(h http.HandlerFunc) http.HandlerFunc {
+		return middleware.Idempotency(idempotency, logger)(h).ServeHTTP
+	}
+
+	{
+		bannerRouter.Handle("/user_banner", with(auth.PermBannerRead, hBanner.GetBanner)).Methods("GET")
+		bannerRouter.Handle("/banner", with(auth.PermBannerRead, hBanner.GetBanners)).Methods("GET")
+		bannerRouter.Handle("/banner", with(auth.PermBannerWrite, idempotent(hBanner.CreateBanners))).Methods("POST")
+		bannerRouter.Handle("/banner", with(auth.PermBannerDelete, hBanner.DeleteBanners)).Methods("DELETE")
+		bannerRouter.Handle("/banner/export", with(auth.PermBannerReadInactive, hBanner.ExportBanners)).Methods("GET")
+		bannerRouter.Handle("/banner/import", with(auth.PermBannerWrite, hBanner.ImportBanners)).Methods("POST")
+		bannerRouter.Handle("/banner/{id}", with(auth.PermBannerWrite, hBanner.UpdateBanner)).Methods("PATCH")
+		bannerRouter.Handle("/banner/{id}", with(auth.PermBannerDelete, hBanner.DeleteBanner)).Methods("DELETE")
+		bannerRouter.Handle("/banner/{id}/versions", with(auth.PermBannerReadInactive, hBanner.GetBannerVersions)).Methods("GET")
+		bannerRouter.Handle("/banner/{id}/versions/{version}/restore", with(auth.PermBannerWrite, hBanner.RestoreBannerVersion)).Methods("POST")
+		bannerRouter.Handle("/banner/{id}/click", with(auth.PermBannerRead, hEvent.Click)).Methods("POST")
+		bannerRouter.Handle("/deletion_jobs/{id}", with(auth.PermBannerDelete, hBanner.GetDeletionJob)).Methods("GET")
+	}
+	{
+		bannerRouter.Handle("/tags", with(auth.PermBannerRead, hTag.GetTags)).Methods("GET")
+		bannerRouter.Handle("/tags", with(auth.PermTagWrite, hTag.CreateTag)).Methods("POST")
+		bannerRouter.Handle("/tags/{id}", with(auth.PermTagWrite, hTag.UpdateTag)).Methods("PATCH")
+		bannerRouter.Handle("/tags/{id}", with(auth.PermTagWrite, hTag.DeleteTag)).Methods("DELETE")
+	}
+	{
+		bannerRouter.Handle("/features", with(auth.PermBannerRead, hFeature.GetFeatures)).Methods("GET")
+		bannerRouter.Handle("/features", with(auth.PermFeatureWrite, hFeature.CreateFeature)).Methods("POST")
+		bannerRouter.Handle("/features/{id}", with(auth.PermFeatureWrite, hFeature.UpdateFeature)).Methods("PATCH")
+		bannerRouter.Handle("/features/{id}", with(auth.PermFeatureWrite, hFeature.DeleteFeature)).Methods("DELETE")
+		bannerRouter.Handle("/features/{id}/schema", with(auth.PermBannerRead, hFeature.GetContentSchema)).Methods("GET")
+		bannerRouter.Handle("/features/{id}/schema", with(auth.PermFeatureWrite, hFeature.SetContentSchema)).Methods("PUT")
+		bannerRouter.Handle("/features/{id}/schema", with(auth.PermFeatureWrite, hFeature.DeleteContentSchema)).Methods("DELETE")
+	}
+	{
+		bannerRouter.Handle("/api_keys", with(auth.PermAPIKeyManage, hKey.GetKeys)).Methods("GET")
+		bannerRouter.Handle("/api_keys", with(auth.PermAPIKeyManage, hKey.IssueKey)).Methods("POST")
+		bannerRouter.Handle("/api_keys/{id}/rotate", with(auth.PermAPIKeyManage, hKey.RotateKey)).Methods("POST")
+		bannerRouter.Handle("/api_keys/{id}", with(auth.PermAPIKeyManage, hKey.RevokeKey)).Methods("DELETE")
+	}
+	{
+		bannerRouter.Handle("/experiments", with(auth.PermExperimentManage, hExperiment.GetExperiments)).Methods("GET")
+		bannerRouter.Handle("/experiments", with(auth.PermExperimentManage, hExperiment.CreateExperiment)).Methods("POST")
+		bannerRouter.Handle("/experiments/{id}", with(auth.PermExperimentManage, hExperiment.GetExperiment)).Methods("GET")
+		bannerRouter.Handle("/experiments/{id}/stop", with(auth.PermExperimentManage, hExperiment.StopExperiment)).Methods("POST")
+	}
+
+	{
+		bannerRouter.Handle("/stats/ctr", with(auth.PermStatsRead, hEvent.GetCTR)).Methods("GET")
+	}
 	{
-		bannerRouter.HandleFunc("/user_banner", hBanner.GetBanner).Methods("GET")
-		bannerRouter.HandleFunc("/banner", hBanner.GetBanners).Methods("GET")
-		bannerRouter.HandleFunc("/banner", hBanner.CreateBanners).Methods("POST")
-		bannerRouter.HandleFunc("/banner/{id}", hBanner.UpdateBanner).Methods("PATCH")
-		bannerRouter.HandleFunc("/banner/{id}", hBanner.DeleteBanner).Methods("DELETE")
+		bannerRouter.Handle("/webhooks", with(auth.PermWebhookManage, hWebhook.GetWebhooks)).Methods("GET")
+		bannerRouter.Handle("/webhooks", with(auth.PermWebhookManage, hWebhook.CreateWebhook)).Methods("POST")
+		bannerRouter.Handle("/webhooks/{id}", with(auth.PermWebhookManage, hWebhook.DeleteWebhook)).Methods("DELETE")
+		bannerRouter.Handle("/webhooks/{id}/deliveries", with(auth.PermWebhookManage, hWebhook.GetDeliveries)).Methods("GET")
+		bannerRouter.Handle("/webhooks/deliveries/{id}/retry", with(auth.PermWebhookManage, hWebhook.RetryDelivery)).Methods("POST")
 	}
 
 	return r
@@ -1,32 +1,71 @@
 package banner
 
 import (
+	"context"
+
 	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
 )
 
 // var _ Usecase = (*)(nil)
 
 type Usecase interface {
-	GetBanner(tagId, featureId int, useLastRevision, isAdmin bool) (interface{}, error)
-	GetBanners(tagId, featureId int, limit, offset int, isAdmin bool) ([]entity.Banner, error)
-	CreateBanner(createBanner *entity.Banner) (int, error)
-	UpdateBanner(updBanner *entity.Banner) error
-	DeleteBanner(bannerId int) error
+	GetBanner(ctx context.Context, principal *auth.Principal, tagId, featureId int, useLastRevision bool, userId string) (*entity.UserBanner, error)
+	GetBanners(ctx context.Context, principal *auth.Principal, tagId, featureId int, limit, offset int) ([]entity.Banner, error)
+	GetBannersPage(ctx context.Context, principal *auth.Principal, tagId, featureId int, cursor entity.BannerCursor, limit int) (*entity.BannerPage, error)
+	CreateBanner(ctx context.Context, principal *auth.Principal, createBanner *entity.Banner) (int, error)
+	UpdateBanner(ctx context.Context, principal *auth.Principal, updBanner *entity.Banner) error
+	DeleteBanner(ctx context.Context, principal *auth.Principal, bannerId int) error
+	GetBannerVersions(ctx context.Context, principal *auth.Principal, bannerId int) ([]entity.BannerVersion, error)
+	RestoreBannerVersion(ctx context.Context, principal *auth.Principal, bannerId, version int) error
+	DeleteBanners(ctx context.Context, principal *auth.Principal, featureId, tagId int) (int, error)
+	GetDeletionJob(ctx context.Context, principal *auth.Principal, jobId int) (*entity.DeletionJob, error)
+	ProcessDeletionBatch(ctx context.Context, batchSize int, retry entity.RetryPolicy) (*entity.DeletionJob, error)
+	ExportBanners(ctx context.Context, principal *auth.Principal, tagId, featureId int, fn func(*entity.Banner) error) error
+	ImportBanners(ctx context.Context, principal *auth.Principal, lines []entity.ImportLine, dryRun bool) (*entity.ImportReport, error)
 }
 
+// Every Repository and Cashe method takes the tenant explicitly, there is no way
+// to reach banners without saying whose banners they are.
+
 // var _ Repository = (*test)(nil)
 type Repository interface {
-	GetBannerById(bannerId int) (*entity.Banner, error)
-	GetBanner(tagId, featureId int, useLastRevision, isAdmin bool) (interface{}, bool, error)
-	GetBanners(tagId, featureId int, limit, offset int, isAdmin bool) ([]entity.Banner, error)
-	CreateBanner(createBanner *entity.Banner) (int, error)
-	UpdateBanner(updBanner *entity.Banner) error
-	DeleteBanner(bannerId int) error
-	CheckIfTagsExist(tagIds []int) (bool, error)
-	CheckIfFeatureIdExist(featureId int) (bool, error)
+	GetBannerById(ctx context.Context, tenantId, bannerId int) (*entity.Banner, error)
+	GetBanner(ctx context.Context, tenantId, tagId, featureId int, useLastRevision, showInactive bool) (*entity.Banner, error)
+	GetBanners(ctx context.Context, tenantId, tagId, featureId int, limit, offset int, showInactive bool) ([]entity.Banner, error)
+	GetBannersPage(ctx context.Context, tenantId, tagId, featureId int, cursor entity.BannerCursor, limit int, showInactive bool) ([]entity.Banner, error)
+	CreateBanner(ctx context.Context, tenantId int, createBanner *entity.Banner, author string) (int, error)
+	UpdateBanner(ctx context.Context, tenantId int, updBanner *entity.Banner, author string) error
+	DeleteBanner(ctx context.Context, tenantId, bannerId int) error
+	CheckIfTagsExist(ctx context.Context, tenantId int, tagIds []int) (bool, error)
+	CheckIfFeatureIdExist(ctx context.Context, tenantId, featureId int) (bool, error)
+	GetContentSchema(ctx context.Context, tenantId, featureId int) ([]byte, error)
+	GetBannerVersions(ctx context.Context, tenantId, bannerId int) ([]entity.BannerVersion, error)
+	GetBannerVersion(ctx context.Context, tenantId, bannerId, version int) (*entity.BannerVersion, error)
+	CreateDeletionJob(ctx context.Context, tenantId, featureId, tagId int) (int, error)
+	GetDeletionJob(ctx context.Context, tenantId, jobId int) (*entity.DeletionJob, error)
+	// ProcessDeletionBatch serves the queue of all tenants, each job carries its own tenant
+	ProcessDeletionBatch(ctx context.Context, batchSize int) (*entity.DeletionJob, error)
+	FailDeletionJob(ctx context.Context, job *entity.DeletionJob) error
+	ExportBanners(ctx context.Context, tenantId, tagId, featureId int, fn func(*entity.Banner) error) error
+	ImportBanners(ctx context.Context, tenantId int, banners []entity.Banner, author string, dryRun bool) ([]entity.ImportResult, error)
 }
 
+// Cashe keeps banners the way users get them, experiment fields aside.
 type Cashe interface {
-	Set(tagID int, featureID int, content interface{}) error
-	Get(tagID int, featureID int) (interface{}, error)
+	// Set never keeps the banner past its EndsAt
+	Set(ctx context.Context, tenantId, tagID int, featureID int, banner *entity.UserBanner) error
+	Get(ctx context.Context, tenantId, tagID int, featureID int) (*entity.UserBanner, error)
+	SetById(ctx context.Context, tenantId int, banner *entity.UserBanner) error
+	GetById(ctx context.Context, tenantId, bannerId int) (*entity.UserBanner, error)
+	// Delete drops every entry the banner may be cached under with its feature and tags
+	Delete(ctx context.Context, tenantId int, banner *entity.Banner) error
+}
+
+// Experiments picks the variant a user gets while an experiment is running on
+// the feature and tag, a nil assignment means there is none. Only an assignment
+// whose banner is actually served is recorded.
+type Experiments interface {
+	Assign(ctx context.Context, tenantId, tagId, featureId int, userId string) (*entity.Assignment, error)
+	RecordAssignment(ctx context.Context, tenantId int, assignment *entity.Assignment, userId string)
 }
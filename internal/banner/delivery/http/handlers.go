@@ -1,55 +1,97 @@
 package http
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/DmitriyKomarovCoder/banner-api/internal/banner"
 	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
 	"github.com/DmitriyKomarovCoder/banner-api/internal/entity/dto"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/event"
 	util "github.com/DmitriyKomarovCoder/banner-api/internal/utils/http"
 	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
 	"github.com/go-playground/validator/v10"
 )
 
 const (
-	bannerIdPath = "id"
+	bannerIdPath      = "id"
+	bannerVersionPath = "version"
+	deletionJobPath   = "id"
+	// userIdHeader is read when the user_id query parameter is missing
+	userIdHeader       = "X-User-Id"
+	experimentHeader   = "X-Experiment-Id"
+	variantHeader      = "X-Experiment-Variant"
+	ndjsonType         = "application/x-ndjson"
+	ifMatchHeader      = "If-Match"
+	ifNoneMatchHeader  = "If-None-Match"
+	etagHeader         = "ETag"
+	cacheControlHeader = "Cache-Control"
+	// user banners differ by caller and by user, shared caches must keep
+	// them apart; "token" is the legacy header of the JWT
+	userBannerVary = "Authorization, X-API-Key, token, X-User-Id"
+	// an import is read into memory and written in one transaction bounded by
+	// the query timeout, so a single banner, the whole body and the number of
+	// banners are all capped
+	maxImportLine  = 1 << 20
+	maxImportBody  = 8 << 20
+	maxImportLines = 1000
+	// pages of the cursor mode of GetBanners
+	defaultPageSize = 100
+	maxPageSize     = util.MaxLimit
+)
+
+var (
+	errEmptyImport     = errors.New("import has no banners")
+	errImportTooLarge  = fmt.Errorf("import is limited to %d bytes and %d banners", maxImportBody, maxImportLines)
+	errVersionRequired = errors.New("If-Match or version is required, read the banner first")
+	errVersionMismatch = errors.New("If-Match and version disagree")
+	errCursorOffset    = errors.New("offset can't be combined with cursor pagination")
+	errCursorSort      = errors.New("sort and order can't change between pages")
 )
 
 type Handler struct {
-	usecase banner.Usecase
-	log     logger.Logger
+	usecase     banner.Usecase
+	impressions event.Recorder
+	// maxAge lets clients reuse a user banner as long as the banner cache does
+	maxAge time.Duration
+	log    logger.Logger
 }
 
-func NewHandler(usecase banner.Usecase, log logger.Logger) *Handler {
+func NewHandler(usecase banner.Usecase, impressions event.Recorder, maxAge time.Duration, log logger.Logger) *Handler {
 	return &Handler{
-		usecase: usecase,
-		log:     log,
+		usecase:     usecase,
+		impressions: impressions,
+		maxAge:      maxAge,
+		log:         log,
 	}
 }
 
 func (h *Handler) GetBanner(w http.ResponseWriter, r *http.Request) {
-	isAdmin := util.GetAuthToken(r)
 	tagIdS := r.URL.Query().Get("tag_id")
 	featureIdS := r.URL.Query().Get("feature_id")
 	lastRevisionS := r.URL.Query().Get("use_last_revision")
 
 	if tagIdS == "" || featureIdS == "" {
-		util.ErrorResponse(w, http.StatusBadRequest, nil, entity.MsgErrorQuery, h.log)
+		util.ErrorResponse(w, http.StatusBadRequest, nil, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
 		return
 	}
 
 	tagId, err := strconv.Atoi(tagIdS)
 	if err != nil {
-		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log)
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
 		return
 	}
 
 	featureId, err := strconv.Atoi(featureIdS)
 	if err != nil {
-		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log)
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
 		return
 	}
 
@@ -57,43 +99,111 @@ func (h *Handler) GetBanner(w http.ResponseWriter, r *http.Request) {
 	if lastRevisionS != "" {
 		lastRevision, err = strconv.ParseBool(lastRevisionS)
 		if err != nil {
-			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log)
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
 			return
 		}
 	} else {
 		lastRevision = false
 	}
 
-	BannerContent, err := h.usecase.GetBanner(tagId, featureId, lastRevision, isAdmin)
+	userId := r.URL.Query().Get("user_id")
+	if userId == "" {
+		userId = r.Header.Get(userIdHeader)
+	}
+
+	userBanner, err := h.usecase.GetBanner(r.Context(), util.GetPrincipal(r), tagId, featureId, lastRevision, userId)
 	if err != nil {
 		if errors.Is(err, entity.ErrorsNotFound) {
-			h.log.Infof("invalid request: %v:", err)
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
 			w.WriteHeader(http.StatusNotFound)
 			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
 		} else {
-			h.log.Error(err.Error())
+			h.log.FromContext(r.Context()).Error(err.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 	}
 
-	util.SuccessResponse(w, http.StatusOK, BannerContent)
+	if userBanner.Variant != "" {
+		w.Header().Set(experimentHeader, strconv.Itoa(userBanner.ExperimentId))
+		w.Header().Set(variantHeader, userBanner.Variant)
+	}
+
+	w.Header().Set(etagHeader, userBanner.ETag)
+	w.Header().Set("Vary", userBannerVary)
+	// the last revision is asked for to skip caches, it may still be revalidated
+	if lastRevision {
+		w.Header().Set(cacheControlHeader, "no-cache")
+	} else {
+		w.Header().Set(cacheControlHeader, cacheControl(h.maxAge, userBanner.EndsAt, time.Now()))
+	}
+
+	if etagMatch(r.Header.Get(ifNoneMatchHeader), userBanner.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// only a sent body is an impression, userBanner is the banner actually
+	// served, the regular one when a variant fell back
+	h.impressions.RecordImpression(entity.Event{
+		TenantId:  util.GetPrincipal(r).TenantId,
+		BannerId:  userBanner.BannerId,
+		FeatureId: featureId,
+		TagId:     tagId,
+		UserId:    userId,
+	})
+	util.SuccessResponse(w, http.StatusOK, userBanner.Content)
+}
+
+// cacheControl lets clients keep a user banner for maxAge but not past the end
+// of its window, an ended banner is not stored at all. Responses depend on the
+// credentials and the tenant, so shared caches must not keep them.
+func cacheControl(maxAge time.Duration, endsAt *time.Time, now time.Time) string {
+	if endsAt != nil {
+		if untilEnd := endsAt.Sub(now); untilEnd < maxAge {
+			maxAge = untilEnd
+		}
+	}
+	seconds := int(maxAge.Seconds())
+	if seconds <= 0 {
+		return "no-store"
+	}
+	return fmt.Sprintf("private, max-age=%d", seconds)
+}
+
+// etagMatch reports whether the If-None-Match header lists etag. The comparison
+// is weak as RFC 9110 asks for If-None-Match, a W/ prefix is ignored.
+func etagMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
 }
 
 func (h *Handler) GetBanners(w http.ResponseWriter, r *http.Request) {
 	tagIdS := r.URL.Query().Get("tag_id")
 	featureIdS := r.URL.Query().Get("feature_id")
 
-	limitS := r.URL.Query().Get("limit")
-	offsetS := r.URL.Query().Get("offset")
-
 	var tagId int
 	var err error
 
 	if tagIdS != "" {
 		tagId, err = strconv.Atoi(tagIdS)
 		if err != nil {
-			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log)
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
 			return
 		}
 	}
@@ -102,71 +212,173 @@ func (h *Handler) GetBanners(w http.ResponseWriter, r *http.Request) {
 	if featureIdS != "" {
 		featureId, err = strconv.Atoi(featureIdS)
 		if err != nil {
-			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log)
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
 			return
 		}
 	}
 
-	var limit, offset = 100, 0
+	// plain limit/offset requests keep getting a bare array
+	query := r.URL.Query()
+	if query.Has("cursor") || query.Get("sort") != "" || query.Get("order") != "" {
+		h.getBannersPage(w, r, tagId, featureId)
+		return
+	}
 
-	if limitS != "" {
-		limit, err = strconv.Atoi(limitS)
-		if err != nil {
-			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log)
+	limit, offset, err := util.GetLimitOffset(r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+		return
+	}
+
+	arrayBanner, err := h.usecase.GetBanners(r.Context(), util.GetPrincipal(r), tagId, featureId, limit, offset)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 	}
 
-	if offsetS != "" {
-		offset, err = strconv.Atoi(offsetS)
-		if err != nil {
-			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log)
+	bannersDTO := dto.BannerToArrayResponseDTO(arrayBanner)
+
+	util.SuccessResponse(w, http.StatusOK, bannersDTO)
+}
+
+// getBannersPage is the cursor mode of GetBanners: the first page is asked for
+// with sort and order, the next ones with the next_cursor of the previous page.
+func (h *Handler) getBannersPage(w http.ResponseWriter, r *http.Request, tagId, featureId int) {
+	query := r.URL.Query()
+	if query.Get("offset") != "" {
+		util.ErrorResponse(w, http.StatusBadRequest, errCursorOffset, errCursorOffset.Error(), h.log.FromContext(r.Context()))
+		return
+	}
+
+	limit := defaultPageSize
+	if limitS := query.Get("limit"); limitS != "" {
+		var err error
+		limit, err = strconv.Atoi(limitS)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			err = fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+			util.ErrorResponse(w, http.StatusBadRequest, err, err.Error(), h.log.FromContext(r.Context()))
 			return
 		}
 	}
 
-	isAdmin := util.GetAuthToken(r)
-	arrayBanner, err := h.usecase.GetBanners(tagId, featureId, limit, offset, isAdmin)
+	cursor, err := pageCursor(query.Get("cursor"), query.Get("sort"), query.Get("order"))
 	if err != nil {
-		if errors.Is(err, entity.ErrorsNotFound) {
-			h.log.Infof("invalid request: %v:", err)
-			w.WriteHeader(http.StatusNotFound)
+		util.ErrorResponse(w, http.StatusBadRequest, err, err.Error(), h.log.FromContext(r.Context()))
+		return
+	}
+
+	page, err := h.usecase.GetBannersPage(r.Context(), util.GetPrincipal(r), tagId, featureId, cursor, limit)
+	if err != nil {
+		if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
 			return
 		} else {
-			h.log.Error(err.Error())
+			h.log.FromContext(r.Context()).Error(err.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 	}
 
-	bannersDTO := dto.BannerToArrayResponseDTO(arrayBanner)
+	util.SuccessResponse(w, http.StatusOK, dto.BannerPageToResponseDTO(*page))
+}
 
-	util.SuccessResponse(w, http.StatusOK, bannersDTO)
+// pageCursor reads the position of a page. Without a token it is the start of
+// the order given by sort (updated_at by default) and order (desc by default),
+// a token carries its own order which sort and order may only repeat.
+func pageCursor(token, sort, order string) (entity.BannerCursor, error) {
+	if sort != "" && sort != entity.SortBannerId && sort != entity.SortCreatedAt && sort != entity.SortUpdatedAt {
+		return entity.BannerCursor{}, fmt.Errorf("sort must be one of %s, %s, %s", entity.SortBannerId, entity.SortCreatedAt, entity.SortUpdatedAt)
+	}
+	if order != "" && order != "asc" && order != "desc" {
+		return entity.BannerCursor{}, errors.New("order must be asc or desc")
+	}
+
+	if token != "" {
+		cursor, err := dto.DecodeBannerCursor(token)
+		if err != nil {
+			return entity.BannerCursor{}, err
+		}
+		if (sort != "" && sort != cursor.Sort) || (order != "" && (order == "desc") != cursor.Desc) {
+			return entity.BannerCursor{}, errCursorSort
+		}
+		return cursor, nil
+	}
+
+	cursor := entity.BannerCursor{Sort: entity.SortUpdatedAt, Desc: order != "asc"}
+	if sort != "" {
+		cursor.Sort = sort
+	}
+	return cursor, nil
 }
 
 func (h *Handler) CreateBanners(w http.ResponseWriter, r *http.Request) {
 	var BannerDTO dto.BannerCreateRequestDTO
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&BannerDTO); err != nil {
-		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log)
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
 		return
 	}
 
 	validate := validator.New()
 	if err := validate.Struct(BannerDTO); err != nil {
-		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log)
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
 		return
 	}
 
 	banner := dto.BannerCreateDToToBanner(BannerDTO)
-	bannerId, err := h.usecase.CreateBanner(&banner)
+	bannerId, err := h.usecase.CreateBanner(r.Context(), util.GetPrincipal(r), &banner)
 	if err != nil {
+		var conflict *entity.BannerConflictError
+		if errors.As(err, &conflict) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			util.SuccessResponse(w, http.StatusConflict, dto.BannerConflictToResponseDTO(*conflict))
+			return
+		}
+
+		var validation *entity.ContentValidationError
+		if errors.As(err, &validation) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			util.SuccessResponse(w, http.StatusUnprocessableEntity, dto.ContentValidationToResponseDTO(*validation))
+			return
+		}
+
+		if errors.Is(err, entity.ErrorsForbidden) {
+			util.ErrorResponse(w, http.StatusForbidden, err, "", h.log.FromContext(r.Context()))
+			return
+		}
+
+		// the external key belongs to another banner
+		if errors.Is(err, entity.ErrorsConflict) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
 		if errors.Is(err, entity.ErrorsNotFound) {
-			h.log.Infof("invalid request: %v:", err)
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
 			w.WriteHeader(http.StatusNotFound)
 			return
+		} else if errors.Is(err, entity.ErrorsNotBody) {
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
 		} else {
-			h.log.Error(err.Error())
+			h.log.FromContext(r.Context()).Error(err.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -178,51 +390,123 @@ func (h *Handler) CreateBanners(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateBanner(w http.ResponseWriter, r *http.Request) {
 	id, err := util.GetValueFromUrl(bannerIdPath, r)
 	if err != nil {
-		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log)
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
 		return
 	}
 
 	var BannerDTO dto.BannerUpdateRequestDTO
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&BannerDTO); err != nil {
-		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log)
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
 		return
 	}
 	banner := dto.BannerUpdateDToToBanner(BannerDTO, id)
 
-	err = h.usecase.UpdateBanner(&banner)
+	banner.Version, err = expectedVersion(r, BannerDTO.Version)
 	if err != nil {
+		if errors.Is(err, errVersionRequired) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			util.SuccessResponse(w, http.StatusPreconditionRequired, entity.ResponseError{ErrMsg: err.Error()})
+			return
+		}
+		util.ErrorResponse(w, http.StatusBadRequest, err, err.Error(), h.log.FromContext(r.Context()))
+		return
+	}
+
+	err = h.usecase.UpdateBanner(r.Context(), util.GetPrincipal(r), &banner)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsStaleVersion) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			util.SuccessResponse(w, http.StatusPreconditionFailed, entity.ResponseError{ErrMsg: entity.ErrorsStaleVersion.Error()})
+			return
+		}
+
+		var conflict *entity.BannerConflictError
+		if errors.As(err, &conflict) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			util.SuccessResponse(w, http.StatusConflict, dto.BannerConflictToResponseDTO(*conflict))
+			return
+		}
+
+		var validation *entity.ContentValidationError
+		if errors.As(err, &validation) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			util.SuccessResponse(w, http.StatusUnprocessableEntity, dto.ContentValidationToResponseDTO(*validation))
+			return
+		}
+
+		if errors.Is(err, entity.ErrorsForbidden) {
+			util.ErrorResponse(w, http.StatusForbidden, err, "", h.log.FromContext(r.Context()))
+			return
+		}
+
 		if errors.Is(err, entity.ErrorsNotFound) {
-			h.log.Infof("invalid request: %v:", err)
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
 			w.WriteHeader(http.StatusNotFound)
 			return
 		} else if errors.Is(err, entity.ErrorsNotBody) {
-			h.log.Infof("invalid request: %v:", err)
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
 			w.WriteHeader(http.StatusBadRequest)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
 		} else {
-			h.log.Error(err.Error())
+			h.log.FromContext(r.Context()).Error(err.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 	}
+	w.Header().Set(etagHeader, versionETag(banner.Version))
 	w.WriteHeader(http.StatusOK)
 }
 
+// expectedVersion is the banner version an update is based on, taken from
+// If-Match or else from the version of the body. Updates without one would
+// silently overwrite changes of others, so they get errVersionRequired.
+func expectedVersion(r *http.Request, bodyVersion int) (int, error) {
+	header := r.Header.Get(ifMatchHeader)
+	if header == "" {
+		if bodyVersion <= 0 {
+			return 0, errVersionRequired
+		}
+		return bodyVersion, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("If-Match must be a banner version like \"3\", got %s", header)
+	}
+	if bodyVersion != 0 && bodyVersion != version {
+		return 0, errVersionMismatch
+	}
+	return version, nil
+}
+
+func versionETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
 func (h *Handler) DeleteBanner(w http.ResponseWriter, r *http.Request) {
 	id, err := util.GetValueFromUrl(bannerIdPath, r)
 	if err != nil {
-		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log)
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
 		return
 	}
 
-	err = h.usecase.DeleteBanner(id)
+	err = h.usecase.DeleteBanner(r.Context(), util.GetPrincipal(r), id)
 	if err != nil {
 		if errors.Is(err, entity.ErrorsNotFound) {
-			h.log.Infof("invalid request: %v:", err)
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
 			w.WriteHeader(http.StatusBadRequest)
 			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
 		} else {
-			h.log.Error(err.Error())
+			h.log.FromContext(r.Context()).Error(err.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -230,3 +514,297 @@ func (h *Handler) DeleteBanner(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *Handler) GetBannerVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(bannerIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	versions, err := h.usecase.GetBannerVersions(r.Context(), util.GetPrincipal(r), id)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.BannerVersionsToResponseDTO(versions))
+}
+
+func (h *Handler) RestoreBannerVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(bannerIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	version, err := util.GetValueFromUrl(bannerVersionPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	err = h.usecase.RestoreBannerVersion(r.Context(), util.GetPrincipal(r), id, version)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsRevisionGone) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			util.SuccessResponse(w, http.StatusConflict, entity.ResponseError{ErrMsg: entity.ErrorsRevisionGone.Error()})
+			return
+		}
+
+		var conflict *entity.BannerConflictError
+		if errors.As(err, &conflict) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			util.SuccessResponse(w, http.StatusConflict, dto.BannerConflictToResponseDTO(*conflict))
+			return
+		}
+
+		var validation *entity.ContentValidationError
+		if errors.As(err, &validation) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			util.SuccessResponse(w, http.StatusUnprocessableEntity, dto.ContentValidationToResponseDTO(*validation))
+			return
+		}
+
+		if errors.Is(err, entity.ErrorsForbidden) {
+			util.ErrorResponse(w, http.StatusForbidden, err, "", h.log.FromContext(r.Context()))
+			return
+		}
+
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) DeleteBanners(w http.ResponseWriter, r *http.Request) {
+	tagIdS := r.URL.Query().Get("tag_id")
+	featureIdS := r.URL.Query().Get("feature_id")
+
+	if tagIdS == "" && featureIdS == "" {
+		util.ErrorResponse(w, http.StatusBadRequest, nil, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+		return
+	}
+
+	var tagId, featureId int
+	var err error
+
+	if tagIdS != "" {
+		tagId, err = strconv.Atoi(tagIdS)
+		if err != nil {
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+			return
+		}
+	}
+
+	if featureIdS != "" {
+		featureId, err = strconv.Atoi(featureIdS)
+		if err != nil {
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+			return
+		}
+	}
+
+	jobId, err := h.usecase.DeleteBanners(r.Context(), util.GetPrincipal(r), featureId, tagId)
+	if err != nil {
+		if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		}
+		h.log.FromContext(r.Context()).Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	util.SuccessResponse(w, http.StatusAccepted, dto.DeletionJobCreatedDTO{JobId: jobId})
+}
+
+func (h *Handler) GetDeletionJob(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(deletionJobPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	job, err := h.usecase.GetDeletionJob(r.Context(), util.GetPrincipal(r), id)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.DeletionJobToResponseDTO(*job))
+}
+
+// ExportBanners streams the banners as NDJSON, one banner with its tags per
+// line, optionally filtered by tag_id and feature_id. The lines can be fed back
+// to ImportBanners.
+func (h *Handler) ExportBanners(w http.ResponseWriter, r *http.Request) {
+	var tagId, featureId int
+	var err error
+
+	if tagIdS := r.URL.Query().Get("tag_id"); tagIdS != "" {
+		tagId, err = strconv.Atoi(tagIdS)
+		if err != nil {
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+			return
+		}
+	}
+
+	if featureIdS := r.URL.Query().Get("feature_id"); featureIdS != "" {
+		featureId, err = strconv.Atoi(featureIdS)
+		if err != nil {
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", ndjsonType)
+	enc := json.NewEncoder(w)
+	written := false
+
+	err = h.usecase.ExportBanners(r.Context(), util.GetPrincipal(r), tagId, featureId, func(banner *entity.Banner) error {
+		written = true
+		return enc.Encode(dto.BannerToLineDTO(*banner))
+	})
+	if err != nil {
+		// the status is gone with the first line, the client sees a cut stream
+		if written {
+			h.log.FromContext(r.Context()).Errorf("export aborted: %v", err)
+			return
+		}
+
+		if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if !written {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// ImportBanners reads NDJSON in the format of ExportBanners and writes all
+// banners in one transaction, banners with a known external_key are updated.
+// With dry_run=true nothing is written. The report has a result per line, it
+// comes with 422 when any line failed and nothing was imported. Imports over
+// maxImportBody or maxImportLines get 413.
+func (h *Handler) ImportBanners(w http.ResponseWriter, r *http.Request) {
+	var dryRun bool
+	var err error
+	if dryRunS := r.URL.Query().Get("dry_run"); dryRunS != "" {
+		dryRun, err = strconv.ParseBool(dryRunS)
+		if err != nil {
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+			return
+		}
+	}
+
+	validate := validator.New()
+	lines := []entity.ImportLine{}
+
+	scanner := bufio.NewScanner(http.MaxBytesReader(w, r.Body, maxImportBody))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	for n := 1; scanner.Scan(); n++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		if len(lines) == maxImportLines {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", errImportTooLarge)
+			util.SuccessResponse(w, http.StatusRequestEntityTooLarge, entity.ResponseError{ErrMsg: errImportTooLarge.Error()})
+			return
+		}
+
+		line := entity.ImportLine{Line: n}
+		var lineDTO dto.BannerLineDTO
+		if err := json.Unmarshal(raw, &lineDTO); err != nil {
+			line.Err = fmt.Errorf("%w: %v", entity.ErrorsNotBody, err)
+		} else if err := validate.Struct(lineDTO); err != nil {
+			line.Banner.ExternalKey = lineDTO.ExternalKey
+			line.Err = fmt.Errorf("%w: %v", entity.ErrorsNotBody, err)
+		} else {
+			line.Banner = dto.BannerLineDTOToBanner(lineDTO)
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", errImportTooLarge)
+			util.SuccessResponse(w, http.StatusRequestEntityTooLarge, entity.ResponseError{ErrMsg: errImportTooLarge.Error()})
+			return
+		}
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	if len(lines) == 0 {
+		util.ErrorResponse(w, http.StatusBadRequest, errEmptyImport, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	report, err := h.usecase.ImportBanners(r.Context(), util.GetPrincipal(r), lines, dryRun)
+	if err != nil {
+		if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	reportDTO := dto.ImportReportToResponseDTO(*report)
+	if reportDTO.Failed > 0 {
+		h.log.FromContext(r.Context()).Infof("invalid request: import: %d of %d lines failed", reportDTO.Failed, len(lines))
+		util.SuccessResponse(w, http.StatusUnprocessableEntity, reportDTO)
+		return
+	}
+
+	util.SuccessResponse(w, http.StatusOK, reportDTO)
+}
@@ -7,104 +7,210 @@ import (
 	"time"
 
 	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ctxutil"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+// liveBanner is the condition for banners users get, the same rule as entity.Banner.Live.
+const liveBanner = `(b.active = true AND (b.starts_at IS NULL OR b.starts_at <= now()) AND (b.ends_at IS NULL OR b.ends_at > now()))`
+
 const (
 	checkTagsExistMSG    = "CheckIfTagsExist repository layer: %w"
 	checkFeatureExistMSG = "CheckIfFeatureIdExist repository layer: %w"
+	getContentSchemaMSG  = "GetContentSchema repository layer: %w"
 	getBannerByIdMSG     = "GetBannerById repository layer: %w"
 	getBannersMSG        = "GetBanners repository layer: %w"
+	getBannersPageMSG    = "GetBannersPage repository layer: %w"
 	createBannerMSG      = "CreateBanner repository layer: %w"
 	updateBannerMSG      = "UpdateBanner repository layer: %w"
 	rmBannerMSG          = "DeleteBanner repository layer: %w"
+	getVersionsMSG       = "GetBannerVersions repository layer: %w"
+	getVersionMSG        = "GetBannerVersion repository layer: %w"
+	exportBannersMSG     = "ExportBanners repository layer: %w"
+	importBannersMSG     = "ImportBanners repository layer: %w"
+	// =============================
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
 	// =============================
 	checkTags = `SELECT COUNT(*) 
 				 FROM tags 
-				 WHERE tag_id = $1;`
+				 WHERE tenant_id = $1 AND tag_id = $2;`
 
 	checkFeature = `SELECT COUNT(*) 
 					FROM features 
-					WHERE feature_id = $1;`
+					WHERE tenant_id = $1 AND feature_id = $2;`
 
-	getBannerById = `SELECT banner_id, content, active, feature_id, created_at, update_at
+	getContentSchema = `SELECT content_schema
+						FROM features
+						WHERE tenant_id = $1 AND feature_id = $2;`
+
+	getBannerById = `SELECT banner_id, version, content, active, starts_at, ends_at, feature_id, created_at, update_at
 				 FROM banners
-				 WHERE banner_id = $1;`
+				 WHERE tenant_id = $1 AND banner_id = $2;`
 
 	getTags = `SELECT tags.tag_id
 			   FROM banners
 			   JOIN banner_tags ON banners.banner_id = banner_tags.banner_id
 			   JOIN tags ON banner_tags.tag_id = tags.tag_id
-			   WHERE banners.banner_id = $1;`
+			   WHERE banners.tenant_id = $1 AND banners.banner_id = $2;`
 
-	getBanner = `SELECT b.content, b.active
+	getBanner = `SELECT b.banner_id, b.content, b.active, b.starts_at, b.ends_at
 				 FROM banners b
 				 JOIN features f ON b.feature_id = f.feature_id
 				 JOIN banner_tags bt ON b.banner_id = bt.banner_id
 				 JOIN tags t ON bt.tag_id = t.tag_id
-				 WHERE b.feature_id = $1 AND bt.tag_id = $2
-				 AND (b.active = true OR $3 = true)
+				 WHERE b.tenant_id = $1 AND b.feature_id = $2 AND bt.tag_id = $3
+				 AND (` + liveBanner + ` OR $4 = true)
 				 ORDER BY b.update_at ASC
 				 LIMIT 1;`
 
 	getBanners = `
 				 SELECT 
 					 b.banner_id, 
+					 b.version, 
+					 b.external_key, 
 					 ARRAY(SELECT bt.tag_id FROM banner_tags bt WHERE bt.banner_id = b.banner_id) AS tag_ids, 
 					 b.feature_id, 
 					 b.content, 
 					 b.active, 
+					 b.starts_at, 
+					 b.ends_at, 
 					 b.created_at, 
 					 b.update_at 
 				 FROM 
 					 banners b 
 				 WHERE 
-					 (b.active = true OR $1 = true)
+					 b.tenant_id = $1 AND (` + liveBanner + ` OR $2 = true)
 			 `
 
-	createBannerSQL = `INSERT INTO banners (content, active, feature_id, created_at) 
-						VALUES ($1, $2, $3, $4)
+	createBannerSQL = `INSERT INTO banners (tenant_id, content, active, starts_at, ends_at, feature_id, created_at, external_key) 
+						VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 						RETURNING banner_id;`
 
-	createBannerTagsSQL = `INSERT INTO banner_tags (banner_id, tag_id) VALUES ($1, $2);`
-
-	rmBannerTagSQL = `DELETE FROM banner_tags WHERE banner_id = $1;`
-	updBannerSQL   = `UPDATE banners SET content = $1, active = $2, feature_id = $3, update_at = $4 WHERE banner_id = $5;`
-	rmBannerSQL    = `DELETE FROM banners WHERE banner_id = $1;`
+	createBannerTagsSQL = `INSERT INTO banner_tags (tenant_id, banner_id, tag_id) VALUES ($1, $2, $3);`
+
+	// tags are ordered, so exporting the same banners twice gives the same lines
+	exportBannersSQL = `SELECT b.banner_id, b.external_key,
+							ARRAY(SELECT bt.tag_id FROM banner_tags bt WHERE bt.banner_id = b.banner_id ORDER BY bt.tag_id),
+							b.feature_id, b.content, b.active, b.starts_at, b.ends_at, b.created_at, b.update_at
+						FROM banners b
+						WHERE b.tenant_id = $1 AND ($2 = 0 OR b.feature_id = $2)
+						AND ($3 = 0 OR EXISTS (SELECT 1 FROM banner_tags bt WHERE bt.banner_id = b.banner_id AND bt.tag_id = $3))
+						ORDER BY b.banner_id;`
+
+	// the payload is the state of the banner when the event happened, for
+	// deletions the state right before the banner is gone
+	writeOutboxSQL = `INSERT INTO outbox (tenant_id, type, banner_id, payload)
+					  SELECT b.tenant_id, $3, b.banner_id, jsonb_build_object(
+						  'event', $3::varchar,
+						  'banner_id', b.banner_id,
+						  'version', b.version,
+						  'external_key', b.external_key,
+						  'feature_id', b.feature_id,
+						  'tag_ids', ARRAY(SELECT bt.tag_id FROM banner_tags bt WHERE bt.banner_id = b.banner_id ORDER BY bt.tag_id),
+						  'content', b.content,
+						  'is_active', b.active,
+						  'starts_at', b.starts_at,
+						  'ends_at', b.ends_at,
+						  'occurred_at', now())
+					  FROM banners b
+					  WHERE b.tenant_id = $1 AND b.banner_id = ANY($2);`
+
+	lockByExternalKeySQL = `SELECT b.banner_id, b.feature_id, ARRAY(SELECT bt.tag_id FROM banner_tags bt WHERE bt.banner_id = b.banner_id)
+							FROM banners b
+							WHERE b.tenant_id = $1 AND b.external_key = $2
+							FOR UPDATE;`
+
+	rmBannerTagSQL = `DELETE FROM banner_tags WHERE tenant_id = $1 AND banner_id = $2;`
+	updBannerSQL   = `UPDATE banners SET content = $3, active = $4, starts_at = $5, ends_at = $6, feature_id = $7, update_at = $8, version = version + 1
+					  WHERE tenant_id = $1 AND banner_id = $2
+					  RETURNING version;`
+	rmBannerSQL   = `DELETE FROM banners WHERE tenant_id = $1 AND banner_id = $2;`
+	lockBannerSQL = `SELECT version FROM banners WHERE tenant_id = $1 AND banner_id = $2 FOR UPDATE;`
+
+	createVersionSQL = `INSERT INTO banner_versions (tenant_id, banner_id, version, content, tag_ids, feature_id, active, starts_at, ends_at, author, created_at)
+						SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, COALESCE($4::int[], '{}'), $5, $6, $7, $8, $9, $10
+						FROM banner_versions
+						WHERE tenant_id = $1 AND banner_id = $2;`
+
+	pruneVersionsSQL = `DELETE FROM banner_versions
+						WHERE tenant_id = $1 AND banner_id = $2
+						AND version <= (SELECT MAX(version) FROM banner_versions WHERE tenant_id = $1 AND banner_id = $2) - $3;`
+
+	getVersionsSQL = `SELECT banner_id, version, content, tag_ids, feature_id, active, starts_at, ends_at, author, created_at
+					  FROM banner_versions
+					  WHERE tenant_id = $1 AND banner_id = $2
+					  ORDER BY version DESC;`
+
+	getVersionSQL = `SELECT banner_id, version, content, tag_ids, feature_id, active, starts_at, ends_at, author, created_at
+					 FROM banner_versions
+					 WHERE tenant_id = $1 AND banner_id = $2 AND version = $3;`
+
+	rmBannerVersionsSQL = `DELETE FROM banner_versions WHERE tenant_id = $1 AND banner_id = $2;`
+
+	conflictingBannersSQL = `SELECT DISTINCT banner_id
+							 FROM banner_feature_tags
+							 WHERE tenant_id = $1 AND feature_id = $2 AND tag_id = ANY($3) AND banner_id <> $4
+							 ORDER BY banner_id;`
+
+	createFeatureTagsSQL = `INSERT INTO banner_feature_tags (tenant_id, feature_id, tag_id, banner_id)
+							SELECT $1, $2, unnest($3::int[]), $4;`
+
+	rmFeatureTagsSQL = `DELETE FROM banner_feature_tags WHERE tenant_id = $1 AND banner_id = $2;`
 )
 
 type repository struct {
-	db *pgxpool.Pool
+	db                *pgxpool.Pool
+	versionsRetention int
+	queryTimeout      time.Duration
 }
 
-func NewRepository(db *pgxpool.Pool) *repository {
+// NewRepository creates postgres repository. versionsRetention limits how many
+// revisions are kept per banner, zero keeps the whole history. queryTimeout
+// bounds every call on top of the caller's context, zero disables it.
+func NewRepository(db *pgxpool.Pool, versionsRetention int, queryTimeout time.Duration) *repository {
 	return &repository{
-		db: db,
+		db:                db,
+		versionsRetention: versionsRetention,
+		queryTimeout:      queryTimeout,
 	}
 }
 
-func (r *repository) GetBanner(tagId, featureId int, useLastRevision, isAdmin bool) (interface{}, bool, error) {
-	var content interface{}
+// GetBanner returns the banner served for the tag and feature. Without
+// showInactive only live banners are found.
+func (r *repository) GetBanner(ctx context.Context, tenantId, tagId, featureId int, useLastRevision, showInactive bool) (*entity.Banner, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
 
-	active := false
-	err := r.db.QueryRow(context.Background(), getBanner, featureId, tagId, isAdmin).Scan(&content, &active)
+	var banner entity.Banner
+	err := r.db.QueryRow(ctx, getBanner, tenantId, featureId, tagId, showInactive).Scan(&banner.BannerId, &banner.Content, &banner.IsActive, &banner.StartsAt, &banner.EndsAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, false, entity.ErrorsNotFound
+			return nil, entity.ErrorsNotFound
 		}
-		return nil, active, err
+		return nil, err
 	}
 
-	return content, active, nil
+	return &banner, nil
+}
+
+// sortColumns maps the orders of banner pages to their columns, banner_id
+// breaks ties and is the only column of its own order.
+var sortColumns = map[string]string{
+	entity.SortBannerId:  "",
+	entity.SortCreatedAt: "b.created_at",
+	entity.SortUpdatedAt: "b.update_at",
 }
 
-func (r *repository) GetBanners(tagId, featureId int, limit, offset int, isAdmin bool) ([]entity.Banner, error) {
-	args := []interface{}{}
+// filterBanners is getBanners narrowed down to the tag and feature, zero ones
+// match any. It returns the query, its args and the number of the next arg.
+func filterBanners(tenantId, tagId, featureId int, showInactive bool) (string, []interface{}, int) {
 	query := getBanners
+	args := []interface{}{tenantId, showInactive}
 
-	args = append(args, isAdmin)
-	count := 2
+	count := 3
 	if tagId != 0 {
 		query += " AND EXISTS (SELECT 1 FROM banner_tags bt WHERE b.banner_id = bt.banner_id AND bt.tag_id = $" + fmt.Sprint(count) + ")"
 		count++
@@ -117,7 +223,18 @@ func (r *repository) GetBanners(tagId, featureId int, limit, offset int, isAdmin
 		args = append(args, featureId)
 	}
 
-	query += " GROUP BY b.banner_id"
+	return query, args, count
+}
+
+func (r *repository) GetBanners(ctx context.Context, tenantId, tagId, featureId int, limit, offset int, showInactive bool) ([]entity.Banner, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query, args, count := filterBanners(tenantId, tagId, featureId, showInactive)
+
+	// without an order postgres may return the rows of a page in any order,
+	// so pages could overlap or skip banners
+	query += " GROUP BY b.banner_id ORDER BY b.banner_id"
 	if limit != 0 {
 		query += " LIMIT $" + fmt.Sprint(count)
 		count++
@@ -130,7 +247,7 @@ func (r *repository) GetBanners(tagId, featureId int, limit, offset int, isAdmin
 		args = append(args, offset)
 	}
 
-	rows, err := r.db.Query(context.Background(), query, args...)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, entity.ErrorsNotFound
@@ -139,12 +256,71 @@ func (r *repository) GetBanners(tagId, featureId int, limit, offset int, isAdmin
 	}
 	defer rows.Close()
 
+	banners, err := scanBanners(rows)
+	if err != nil {
+		return nil, fmt.Errorf(getBannersMSG, err)
+	}
+	return banners, nil
+}
+
+// GetBannersPage returns up to limit banners following the cursor in its order.
+// Pages are read by keyset, so they stay as fast at the end as at the start and
+// don't shift when banners are added before them.
+func (r *repository) GetBannersPage(ctx context.Context, tenantId, tagId, featureId int, cursor entity.BannerCursor, limit int, showInactive bool) ([]entity.Banner, error) {
+	column, ok := sortColumns[cursor.Sort]
+	if !ok {
+		return nil, fmt.Errorf(getBannersPageMSG, fmt.Errorf("unknown sort %q", cursor.Sort))
+	}
+
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query, args, count := filterBanners(tenantId, tagId, featureId, showInactive)
+
+	cmp, direction := ">", "ASC"
+	if cursor.Desc {
+		cmp, direction = "<", "DESC"
+	}
+
+	if cursor.BannerId != 0 {
+		if column == "" {
+			query += fmt.Sprintf(" AND b.banner_id %s $%d", cmp, count)
+			args = append(args, cursor.BannerId)
+		} else {
+			query += fmt.Sprintf(" AND (%s, b.banner_id) %s ($%d::timestamp, $%d)", column, cmp, count, count+1)
+			args = append(args, cursor.Time, cursor.BannerId)
+		}
+	}
+
+	if column == "" {
+		query += fmt.Sprintf(" ORDER BY b.banner_id %s", direction)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, b.banner_id %s", column, direction, direction)
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf(getBannersPageMSG, err)
+	}
+	defer rows.Close()
+
+	banners, err := scanBanners(rows)
+	if err != nil {
+		return nil, fmt.Errorf(getBannersPageMSG, err)
+	}
+	return banners, nil
+}
+
+// scanBanners reads the rows of getBanners.
+func scanBanners(rows pgx.Rows) ([]entity.Banner, error) {
 	banners := []entity.Banner{}
 	for rows.Next() {
 		var banner entity.Banner
 		var tagIds []int
-		if err := rows.Scan(&banner.BannerId, &tagIds, &banner.FeatureId, &banner.Content, &banner.IsActive, &banner.CreatedDate, &banner.UpdateDate); err != nil {
-			return nil, fmt.Errorf(getBannersMSG, err)
+		if err := rows.Scan(&banner.BannerId, &banner.Version, &banner.ExternalKey, &tagIds, &banner.FeatureId, &banner.Content, &banner.IsActive, &banner.StartsAt, &banner.EndsAt, &banner.CreatedDate, &banner.UpdateDate); err != nil {
+			return nil, err
 		}
 
 		banner.TagsId = tagIds
@@ -153,87 +329,305 @@ func (r *repository) GetBanners(tagId, featureId int, limit, offset int, isAdmin
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf(getBannersMSG, err)
+		return nil, err
 	}
 
 	return banners, nil
 }
 
-func (r *repository) CreateBanner(createBanner *entity.Banner) (int, error) {
-	tx, err := r.db.Begin(context.Background())
+func (r *repository) CreateBanner(ctx context.Context, tenantId int, createBanner *entity.Banner, author string) (int, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return 0, fmt.Errorf(createBannerMSG, err)
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(ctx)
 
-	var bannerId int
-	err = tx.QueryRow(context.Background(), createBannerSQL, createBanner.Content, createBanner.IsActive, createBanner.FeatureId, time.Now()).Scan(&bannerId)
+	bannerId, err := r.createBanner(ctx, tx, tenantId, createBanner, author)
 	if err != nil {
 		return 0, fmt.Errorf(createBannerMSG, err)
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, fmt.Errorf(createBannerMSG, err)
+	}
+
+	return bannerId, nil
+}
+
+// createBanner writes the banner with its tags and first revision inside tx. An
+// external key another banner already has gives ErrorsConflict.
+func (r *repository) createBanner(ctx context.Context, tx pgx.Tx, tenantId int, createBanner *entity.Banner, author string) (int, error) {
+	var bannerId int
+	err := tx.QueryRow(ctx, createBannerSQL, tenantId, createBanner.Content, createBanner.IsActive, createBanner.StartsAt, createBanner.EndsAt, createBanner.FeatureId, time.Now(), createBanner.ExternalKey).Scan(&bannerId)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return 0, entity.ErrorsConflict
+		}
+		return 0, err
+	}
+
 	for _, tagId := range createBanner.TagsId {
-		_, err = tx.Exec(context.Background(), createBannerTagsSQL, bannerId, tagId)
+		_, err = tx.Exec(ctx, createBannerTagsSQL, tenantId, bannerId, tagId)
 		if err != nil {
-			return 0, fmt.Errorf(createBannerMSG, err)
+			return 0, err
 		}
 	}
 
-	err = tx.Commit(context.Background())
-	if err != nil {
-		return 0, fmt.Errorf(createBannerMSG, err)
+	createBanner.BannerId = bannerId
+	if err := r.bindFeatureTags(ctx, tx, tenantId, createBanner); err != nil {
+		return 0, err
+	}
+
+	if err := r.createVersion(ctx, tx, tenantId, createBanner, author); err != nil {
+		return 0, err
+	}
+
+	if err := writeOutbox(ctx, tx, tenantId, entity.BannerCreated, []int{bannerId}); err != nil {
+		return 0, err
 	}
 
 	return bannerId, nil
 }
 
-func (r *repository) UpdateBanner(updBanner *entity.Banner) error {
-	tx, err := r.db.Begin(context.Background())
+func (r *repository) UpdateBanner(ctx context.Context, tenantId int, updBanner *entity.Banner, author string) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf(updateBannerMSG, err)
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(ctx)
+
+	if err := r.updateBanner(ctx, tx, tenantId, updBanner, author); err != nil {
+		return fmt.Errorf(updateBannerMSG, err)
+	}
 
-	_, err = tx.Exec(context.Background(), rmBannerTagSQL, updBanner.BannerId)
+	err = tx.Commit(ctx)
 	if err != nil {
 		return fmt.Errorf(updateBannerMSG, err)
 	}
 
-	_, err = tx.Exec(context.Background(), updBannerSQL, updBanner.Content, updBanner.IsActive, updBanner.FeatureId, time.Now(), updBanner.BannerId)
+	return nil
+}
+
+// updateBanner replaces the banner, its tags and writes a new revision inside tx.
+// A non-zero Version of updBanner must still be the current one, otherwise
+// ErrorsStaleVersion is returned; Version is set to the new revision.
+func (r *repository) updateBanner(ctx context.Context, tx pgx.Tx, tenantId int, updBanner *entity.Banner, author string) error {
+	// lock the banner row so that concurrent updates get sequential version numbers
+	var version int
+	err := tx.QueryRow(ctx, lockBannerSQL, tenantId, updBanner.BannerId).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.ErrorsNotFound
+		}
+		return err
+	}
+
+	if updBanner.Version != 0 && updBanner.Version != version {
+		return entity.ErrorsStaleVersion
+	}
+
+	_, err = tx.Exec(ctx, rmBannerTagSQL, tenantId, updBanner.BannerId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, rmFeatureTagsSQL, tenantId, updBanner.BannerId)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, updBannerSQL, tenantId, updBanner.BannerId, updBanner.Content, updBanner.IsActive, updBanner.StartsAt, updBanner.EndsAt, updBanner.FeatureId, time.Now()).Scan(&updBanner.Version)
+	if err != nil {
+		return err
+	}
 
 	for _, tagId := range updBanner.TagsId {
-		_, err = tx.Exec(context.Background(), createBannerTagsSQL, updBanner.BannerId, tagId)
+		_, err = tx.Exec(ctx, createBannerTagsSQL, tenantId, updBanner.BannerId, tagId)
 		if err != nil {
-			return fmt.Errorf(updateBannerMSG, err)
+			return err
 		}
 	}
 
-	err = tx.Commit(context.Background())
+	if err := r.bindFeatureTags(ctx, tx, tenantId, updBanner); err != nil {
+		return err
+	}
+
+	if err := r.createVersion(ctx, tx, tenantId, updBanner, author); err != nil {
+		return err
+	}
+
+	return writeOutbox(ctx, tx, tenantId, entity.BannerUpdated, []int{updBanner.BannerId})
+}
+
+// ExportBanners calls fn for every banner of the tenant, inactive ones included,
+// while the rows are still being read. Zero tagId or featureId matches any. The
+// whole export, the client reading it included, has to fit in queryTimeout.
+func (r *repository) ExportBanners(ctx context.Context, tenantId, tagId, featureId int, fn func(*entity.Banner) error) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, exportBannersSQL, tenantId, featureId, tagId)
 	if err != nil {
-		return fmt.Errorf(updateBannerMSG, err)
+		return fmt.Errorf(exportBannersMSG, err)
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var banner entity.Banner
+		if err := rows.Scan(&banner.BannerId, &banner.ExternalKey, &banner.TagsId, &banner.FeatureId, &banner.Content,
+			&banner.IsActive, &banner.StartsAt, &banner.EndsAt, &banner.CreatedDate, &banner.UpdateDate); err != nil {
+			return fmt.Errorf(exportBannersMSG, err)
+		}
 
+		if err := fn(&banner); err != nil {
+			return fmt.Errorf(exportBannersMSG, err)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf(exportBannersMSG, err)
+	}
 	return nil
 }
 
-func (r *repository) DeleteBanner(bannerId int) error {
-	tx, err := r.db.Begin(context.Background())
+// ImportBanners creates or updates the banners in a single transaction, a banner
+// whose external key is already taken updates that banner. Each banner runs in
+// its own savepoint, so a failed one is reported and the rest still go through
+// to find their own errors. The transaction is only committed when nothing
+// failed and dryRun is false. Results follow the order of banners, errors of
+// single banners are ErrorsNotFound or ErrorsConflict, anything else aborts the
+// whole import.
+func (r *repository) ImportBanners(ctx context.Context, tenantId int, banners []entity.Banner, author string, dryRun bool) ([]entity.ImportResult, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf(importBannersMSG, err)
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]entity.ImportResult, 0, len(banners))
+	failed := false
+	for i := range banners {
+		result, err := r.importBanner(ctx, tx, tenantId, &banners[i], author)
+		if err != nil {
+			return nil, fmt.Errorf(importBannersMSG, err)
+		}
+		failed = failed || result.Err != nil
+		results = append(results, result)
+	}
+
+	if dryRun || failed {
+		return results, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf(importBannersMSG, err)
+	}
+	return results, nil
+}
+
+func (r *repository) importBanner(ctx context.Context, tx pgx.Tx, tenantId int, banner *entity.Banner, author string) (entity.ImportResult, error) {
+	// nested Begin is a savepoint
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return entity.ImportResult{}, err
+	}
+	defer sp.Rollback(ctx)
+
+	result := entity.ImportResult{Action: entity.ImportCreated}
+	if banner.ExternalKey != nil {
+		var previous entity.Banner
+		err := sp.QueryRow(ctx, lockByExternalKeySQL, tenantId, *banner.ExternalKey).Scan(&previous.BannerId, &previous.FeatureId, &previous.TagsId)
+		if err == nil {
+			banner.BannerId = previous.BannerId
+			result.Action, result.Previous = entity.ImportUpdated, &previous
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return entity.ImportResult{}, err
+		}
+	}
+
+	if result.Action == entity.ImportUpdated {
+		err = r.updateBanner(ctx, sp, tenantId, banner, author)
+	} else {
+		banner.BannerId, err = r.createBanner(ctx, sp, tenantId, banner, author)
+	}
+
+	if err != nil {
+		err = constraintError(err)
+		if !errors.Is(err, entity.ErrorsNotFound) && !errors.Is(err, entity.ErrorsConflict) {
+			return entity.ImportResult{}, err
+		}
+		return entity.ImportResult{Action: entity.ImportFailed, Err: err}, nil
+	}
+
+	if err := sp.Commit(ctx); err != nil {
+		return entity.ImportResult{}, err
+	}
+
+	result.BannerId = banner.BannerId
+	return result, nil
+}
+
+// constraintError maps a missing feature or tag to ErrorsNotFound, the other
+// constraint errors are already mapped where they happen.
+func constraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return entity.ErrorsNotFound
+	}
+	return err
+}
+
+func (r *repository) DeleteBanner(ctx context.Context, tenantId, bannerId int) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf(rmBannerMSG, err)
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(context.Background(), rmBannerTagSQL, bannerId)
+	// written first, the event carries the tags that are deleted next
+	if err := writeOutbox(ctx, tx, tenantId, entity.BannerDeleted, []int{bannerId}); err != nil {
+		return fmt.Errorf(rmBannerMSG, err)
+	}
+
+	_, err = tx.Exec(ctx, rmBannerTagSQL, tenantId, bannerId)
 	if err != nil {
 		return fmt.Errorf(rmBannerMSG, err)
 	}
 
-	_, err = tx.Exec(context.Background(), rmBannerSQL, bannerId)
+	_, err = tx.Exec(ctx, rmFeatureTagsSQL, tenantId, bannerId)
 	if err != nil {
 		return fmt.Errorf(rmBannerMSG, err)
 	}
 
-	err = tx.Commit(context.Background())
+	_, err = tx.Exec(ctx, rmBannerVersionsSQL, tenantId, bannerId)
+	if err != nil {
+		return fmt.Errorf(rmBannerMSG, err)
+	}
+
+	cmdTag, err := tx.Exec(ctx, rmBannerSQL, tenantId, bannerId)
+	if err != nil {
+		return fmt.Errorf(rmBannerMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(rmBannerMSG, entity.ErrorsNotFound)
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return fmt.Errorf(rmBannerMSG, err)
 	}
@@ -241,14 +635,17 @@ func (r *repository) DeleteBanner(bannerId int) error {
 	return nil
 }
 
-func (r *repository) CheckIfTagsExist(tagIds []int) (bool, error) {
+func (r *repository) CheckIfTagsExist(ctx context.Context, tenantId int, tagIds []int) (bool, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	if len(tagIds) == 0 {
 		return false, fmt.Errorf(checkTagsExistMSG, entity.ErrorsNotFound)
 	}
 
 	var count, countRow int
 	for _, id := range tagIds {
-		err := r.db.QueryRow(context.Background(), checkTags, id).Scan(&countRow)
+		err := r.db.QueryRow(ctx, checkTags, tenantId, id).Scan(&countRow)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				return false, fmt.Errorf(checkTagsExistMSG, entity.ErrorsNotFound)
@@ -261,9 +658,12 @@ func (r *repository) CheckIfTagsExist(tagIds []int) (bool, error) {
 	return count == len(tagIds), nil
 }
 
-func (r *repository) CheckIfFeatureIdExist(featureId int) (bool, error) {
+func (r *repository) CheckIfFeatureIdExist(ctx context.Context, tenantId, featureId int) (bool, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	var count int
-	err := r.db.QueryRow(context.Background(), checkFeature, featureId).Scan(&count)
+	err := r.db.QueryRow(ctx, checkFeature, tenantId, featureId).Scan(&count)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return false, fmt.Errorf(checkFeatureExistMSG, entity.ErrorsNotFound)
@@ -273,12 +673,34 @@ func (r *repository) CheckIfFeatureIdExist(featureId int) (bool, error) {
 	return count != 0, nil
 }
 
-func (r *repository) GetBannerById(bannerId int) (*entity.Banner, error) {
+// GetContentSchema returns the schema of the feature, nil when it has none.
+func (r *repository) GetContentSchema(ctx context.Context, tenantId, featureId int) ([]byte, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var contentSchema []byte
+	err := r.db.QueryRow(ctx, getContentSchema, tenantId, featureId).Scan(&contentSchema)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(getContentSchemaMSG, entity.ErrorsNotFound)
+		}
+		return nil, fmt.Errorf(getContentSchemaMSG, err)
+	}
+	return contentSchema, nil
+}
+
+func (r *repository) GetBannerById(ctx context.Context, tenantId, bannerId int) (*entity.Banner, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	var banner entity.Banner
-	err := r.db.QueryRow(context.Background(), getBannerById, bannerId).Scan(
+	err := r.db.QueryRow(ctx, getBannerById, tenantId, bannerId).Scan(
 		&banner.BannerId,
+		&banner.Version,
 		&banner.Content,
 		&banner.IsActive,
+		&banner.StartsAt,
+		&banner.EndsAt,
 		&banner.FeatureId,
 		&banner.CreatedDate,
 		&banner.UpdateDate,
@@ -290,7 +712,7 @@ func (r *repository) GetBannerById(bannerId int) (*entity.Banner, error) {
 		return &banner, fmt.Errorf(getBannerByIdMSG, err)
 	}
 
-	rows, err := r.db.Query(context.Background(), getTags, bannerId)
+	rows, err := r.db.Query(ctx, getTags, tenantId, bannerId)
 	defer rows.Close()
 
 	if err != nil {
@@ -312,3 +734,153 @@ func (r *repository) GetBannerById(bannerId int) (*entity.Banner, error) {
 
 	return &banner, nil
 }
+
+func (r *repository) GetBannerVersions(ctx context.Context, tenantId, bannerId int) ([]entity.BannerVersion, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, getVersionsSQL, tenantId, bannerId)
+	if err != nil {
+		return nil, fmt.Errorf(getVersionsMSG, err)
+	}
+	defer rows.Close()
+
+	versions := []entity.BannerVersion{}
+	for rows.Next() {
+		var version entity.BannerVersion
+		if err := rows.Scan(
+			&version.BannerId,
+			&version.Version,
+			&version.Content,
+			&version.TagsId,
+			&version.FeatureId,
+			&version.IsActive,
+			&version.StartsAt,
+			&version.EndsAt,
+			&version.Author,
+			&version.CreatedDate,
+		); err != nil {
+			return nil, fmt.Errorf(getVersionsMSG, err)
+		}
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(getVersionsMSG, err)
+	}
+
+	return versions, nil
+}
+
+func (r *repository) GetBannerVersion(ctx context.Context, tenantId, bannerId, version int) (*entity.BannerVersion, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var bannerVersion entity.BannerVersion
+	err := r.db.QueryRow(ctx, getVersionSQL, tenantId, bannerId, version).Scan(
+		&bannerVersion.BannerId,
+		&bannerVersion.Version,
+		&bannerVersion.Content,
+		&bannerVersion.TagsId,
+		&bannerVersion.FeatureId,
+		&bannerVersion.IsActive,
+		&bannerVersion.StartsAt,
+		&bannerVersion.EndsAt,
+		&bannerVersion.Author,
+		&bannerVersion.CreatedDate,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(getVersionMSG, entity.ErrorsNotFound)
+		}
+		return nil, fmt.Errorf(getVersionMSG, err)
+	}
+
+	return &bannerVersion, nil
+}
+
+// createVersion stores a snapshot of the banner as its next revision and drops
+// revisions that fall out of the retention window.
+func (r *repository) createVersion(ctx context.Context, tx pgx.Tx, tenantId int, banner *entity.Banner, author string) error {
+	_, err := tx.Exec(ctx, createVersionSQL, tenantId, banner.BannerId, banner.Content, banner.TagsId,
+		banner.FeatureId, banner.IsActive, banner.StartsAt, banner.EndsAt, author, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if r.versionsRetention > 0 {
+		_, err = tx.Exec(ctx, pruneVersionsSQL, tenantId, banner.BannerId, r.versionsRetention)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeOutbox records the event for every banner in bannerIds inside tx, so the
+// event exists exactly when the change is committed.
+func writeOutbox(ctx context.Context, tx pgx.Tx, tenantId int, eventType string, bannerIds []int) error {
+	_, err := tx.Exec(ctx, writeOutboxSQL, tenantId, bannerIds, eventType)
+	return err
+}
+
+// bindFeatureTags claims the banner's (feature_id, tag_id) pairs. Pairs already
+// owned by another banner are reported as BannerConflictError; the primary key on
+// banner_feature_tags catches writers that raced past the check.
+func (r *repository) bindFeatureTags(ctx context.Context, tx pgx.Tx, tenantId int, banner *entity.Banner) error {
+	conflicts, err := r.conflictingBanners(ctx, tx, tenantId, banner)
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) > 0 {
+		return &entity.BannerConflictError{BannerIds: conflicts}
+	}
+
+	// the insert runs in a savepoint, a unique violation aborts only the savepoint
+	// and the winner is looked up on the same connection. Going to the pool
+	// instead would deadlock concurrent writers once the pool is exhausted.
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = savepoint.Exec(ctx, createFeatureTagsSQL, tenantId, banner.FeatureId, banner.TagsId, banner.BannerId)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+			return err
+		}
+
+		if err := savepoint.Rollback(ctx); err != nil {
+			return err
+		}
+		conflicts, err = r.conflictingBanners(ctx, tx, tenantId, banner)
+		if err != nil {
+			return err
+		}
+		return &entity.BannerConflictError{BannerIds: conflicts}
+	}
+
+	return savepoint.Commit(ctx)
+}
+
+func (r *repository) conflictingBanners(ctx context.Context, tx pgx.Tx, tenantId int, banner *entity.Banner) ([]int, error) {
+	rows, err := tx.Query(ctx, conflictingBannersSQL, tenantId, banner.FeatureId, banner.TagsId, banner.BannerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bannerIds := []int{}
+	for rows.Next() {
+		var bannerId int
+		if err := rows.Scan(&bannerId); err != nil {
+			return nil, err
+		}
+		bannerIds = append(bannerIds, bannerId)
+	}
+
+	return bannerIds, rows.Err()
+}
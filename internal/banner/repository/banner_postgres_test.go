@@ -0,0 +1,558 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/migrations"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/migrate"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	tenantA = 1
+	tenantB = 2
+)
+
+// newTestDB connects to TEST_DATABASE_URL and migrates a fresh schema, so tests
+// never touch existing data.
+func newTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	db := newTestSchema(t)
+	migrateTestDB(t, db)
+	return db
+}
+
+// newTestSchema connects to an empty schema of TEST_DATABASE_URL.
+func newTestSchema(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	connStr := os.Getenv("TEST_DATABASE_URL")
+	if connStr == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	admin, err := pgxpool.Connect(ctx, connStr)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	schema := fmt.Sprintf("banner_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		admin.Close()
+		t.Fatal("Expected nil error, got", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+		admin.Close()
+	})
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	poolConfig.ConnConfig.RuntimeParams["search_path"] = schema
+
+	db, err := pgxpool.ConnectConfig(ctx, poolConfig)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	t.Cleanup(db.Close)
+
+	return db
+}
+
+func migrateTestDB(t *testing.T, db *pgxpool.Pool) {
+	t.Helper()
+
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+}
+
+func seedTenant(t *testing.T, db *pgxpool.Pool, tenantId int) (featureId int, tagIds []int) {
+	t.Helper()
+
+	ctx := context.Background()
+	err := db.QueryRow(ctx, `INSERT INTO features (tenant_id, name) VALUES ($1, 'Feature') RETURNING feature_id;`, tenantId).Scan(&featureId)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var tagId int
+		err := db.QueryRow(ctx, `INSERT INTO tags (tenant_id, name) VALUES ($1, 'Tag') RETURNING tag_id;`, tenantId).Scan(&tagId)
+		if err != nil {
+			t.Fatal("Expected nil error, got", err)
+		}
+		tagIds = append(tagIds, tagId)
+	}
+
+	return featureId, tagIds
+}
+
+func TestRepository_TenantIsolation(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db, 3, 5*time.Second)
+	ctx := context.Background()
+
+	featureA, tagsA := seedTenant(t, db, tenantA)
+	featureB, tagsB := seedTenant(t, db, tenantB)
+
+	active := true
+	bannerA := &entity.Banner{
+		TagsId:    tagsA,
+		FeatureId: featureA,
+		Content:   map[string]interface{}{"title": "tenant A"},
+		IsActive:  &active,
+	}
+	bannerId, err := repo.CreateBanner(ctx, tenantA, bannerA, "alice")
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	t.Run("Read", func(t *testing.T) {
+		if _, err := repo.GetBannerById(ctx, tenantB, bannerId); !errors.Is(err, entity.ErrorsNotFound) {
+			t.Error("Expected not found, got", err)
+		}
+		if _, err := repo.GetBanner(ctx, tenantB, tagsA[0], featureA, true, true); !errors.Is(err, entity.ErrorsNotFound) {
+			t.Error("Expected not found, got", err)
+		}
+		versions, err := repo.GetBannerVersions(ctx, tenantB, bannerId)
+		if err != nil || len(versions) != 0 {
+			t.Errorf("Expected no versions, got %v, %v", versions, err)
+		}
+
+		banners, err := repo.GetBanners(ctx, tenantB, 0, 0, 0, 0, true)
+		if err != nil {
+			t.Fatal("Expected nil error, got", err)
+		}
+		if len(banners) != 0 {
+			t.Errorf("Expected no banners of tenant %d, got %v", tenantB, banners)
+		}
+	})
+
+	t.Run("Write", func(t *testing.T) {
+		upd := &entity.Banner{
+			BannerId:  bannerId,
+			TagsId:    tagsB,
+			FeatureId: featureB,
+			Content:   map[string]interface{}{"title": "hijacked"},
+			IsActive:  &active,
+		}
+		if err := repo.UpdateBanner(ctx, tenantB, upd, "mallory"); !errors.Is(err, entity.ErrorsNotFound) {
+			t.Error("Expected not found, got", err)
+		}
+		if err := repo.DeleteBanner(ctx, tenantB, bannerId); !errors.Is(err, entity.ErrorsNotFound) {
+			t.Error("Expected not found, got", err)
+		}
+	})
+
+	t.Run("Foreign references", func(t *testing.T) {
+		flag, err := repo.CheckIfTagsExist(ctx, tenantB, tagsA)
+		if err != nil || flag {
+			t.Errorf("Expected tags of tenant %d to be missing, got %v, %v", tenantA, flag, err)
+		}
+		flag, err = repo.CheckIfFeatureIdExist(ctx, tenantB, featureA)
+		if err != nil || flag {
+			t.Errorf("Expected feature of tenant %d to be missing, got %v, %v", tenantA, flag, err)
+		}
+
+		// bypasses the usecase checks, the schema itself must reject the link
+		foreign := &entity.Banner{
+			TagsId:    tagsA,
+			FeatureId: featureB,
+			Content:   map[string]interface{}{"title": "tenant B"},
+			IsActive:  &active,
+		}
+		if _, err := repo.CreateBanner(ctx, tenantB, foreign, "mallory"); err == nil {
+			t.Error("Expected error linking tags of another tenant")
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		if _, err := repo.GetBannerById(cancelled, tenantA, bannerId); !errors.Is(err, context.Canceled) {
+			t.Error("Expected context.Canceled, got", err)
+		}
+	})
+
+	banner, err := repo.GetBanner(ctx, tenantA, tagsA[0], featureA, true, false)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if got := banner.Content["title"]; got != "tenant A" {
+		t.Errorf("Expected banner of tenant %d untouched, got %v", tenantA, got)
+	}
+}
+
+func TestRepository_Schedule(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db, 3, 5*time.Second)
+	ctx := context.Background()
+
+	featureId, tagIds := seedTenant(t, db, tenantA)
+
+	active := true
+	startsAt := time.Now().Add(-2 * time.Hour)
+	endsAt := time.Now().Add(-time.Hour)
+	expired := &entity.Banner{
+		TagsId:    tagIds[:1],
+		FeatureId: featureId,
+		Content:   map[string]interface{}{"title": "expired"},
+		IsActive:  &active,
+		StartsAt:  &startsAt,
+		EndsAt:    &endsAt,
+	}
+	if _, err := repo.CreateBanner(ctx, tenantA, expired, "alice"); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	if _, err := repo.GetBanner(ctx, tenantA, tagIds[0], featureId, true, false); !errors.Is(err, entity.ErrorsNotFound) {
+		t.Error("Expected expired banner to be hidden, got", err)
+	}
+
+	banner, err := repo.GetBanner(ctx, tenantA, tagIds[0], featureId, true, true)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if banner.EndsAt == nil || !banner.EndsAt.Equal(endsAt.Truncate(time.Microsecond)) {
+		t.Errorf("Expected ends_at %v, got %v", endsAt, banner.EndsAt)
+	}
+
+	banners, err := repo.GetBanners(ctx, tenantA, 0, featureId, 0, 0, false)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if len(banners) != 0 {
+		t.Errorf("Expected no live banners, got %v", banners)
+	}
+
+	invalid := &entity.Banner{
+		TagsId:    tagIds[1:],
+		FeatureId: featureId,
+		Content:   map[string]interface{}{"title": "backwards"},
+		IsActive:  &active,
+		StartsAt:  &endsAt,
+		EndsAt:    &startsAt,
+	}
+	if _, err := repo.CreateBanner(ctx, tenantA, invalid, "alice"); err == nil {
+		t.Error("Expected error for a window that ends before it starts")
+	}
+}
+
+func TestRepository_ImportBanners(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db, 3, 5*time.Second)
+	ctx := context.Background()
+
+	featureId, tagIds := seedTenant(t, db, tenantA)
+
+	active := true
+	key := "promo"
+	banners := []entity.Banner{
+		{ExternalKey: &key, TagsId: tagIds[:1], FeatureId: featureId, Content: map[string]interface{}{"title": "v1"}, IsActive: &active},
+		// claims the pair of the first banner
+		{TagsId: tagIds[:1], FeatureId: featureId, Content: map[string]interface{}{"title": "dup"}, IsActive: &active},
+	}
+
+	results, err := repo.ImportBanners(ctx, tenantA, banners, "alice", false)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if results[0].Action != entity.ImportCreated || !errors.Is(results[1].Err, entity.ErrorsConflict) {
+		t.Fatalf("Expected created and conflict, got %+v", results)
+	}
+
+	if all, _ := repo.GetBanners(ctx, tenantA, 0, 0, 0, 0, true); len(all) != 0 {
+		t.Fatalf("Expected a failed import to roll back, got %v", all)
+	}
+
+	results, err = repo.ImportBanners(ctx, tenantA, banners[:1], "alice", false)
+	if err != nil || results[0].Action != entity.ImportCreated {
+		t.Fatalf("Expected the banner to be created, got %+v, %v", results, err)
+	}
+	createdId := results[0].BannerId
+
+	banners[0].Content = map[string]interface{}{"title": "v2"}
+	results, err = repo.ImportBanners(ctx, tenantA, banners[:1], "alice", false)
+	if err != nil || results[0].Action != entity.ImportUpdated || results[0].BannerId != createdId {
+		t.Fatalf("Expected banner %d to be updated, got %+v, %v", createdId, results, err)
+	}
+
+	var exported []entity.Banner
+	err = repo.ExportBanners(ctx, tenantA, tagIds[0], featureId, func(banner *entity.Banner) error {
+		exported = append(exported, *banner)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if len(exported) != 1 || exported[0].Content["title"] != "v2" || *exported[0].ExternalKey != key {
+		t.Errorf("Expected the updated banner in the export, got %+v", exported)
+	}
+}
+
+func TestRepository_UpdateVersion(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db, 3, 5*time.Second)
+	ctx := context.Background()
+
+	featureId, tagIds := seedTenant(t, db, tenantA)
+
+	active := true
+	bannerId, err := repo.CreateBanner(ctx, tenantA, &entity.Banner{
+		TagsId:    tagIds[:1],
+		FeatureId: featureId,
+		Content:   map[string]interface{}{"title": "v1"},
+		IsActive:  &active,
+	}, "alice")
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	update := func(version int, title string) (*entity.Banner, error) {
+		banner := &entity.Banner{
+			BannerId:  bannerId,
+			Version:   version,
+			TagsId:    tagIds[:1],
+			FeatureId: featureId,
+			Content:   map[string]interface{}{"title": title},
+			IsActive:  &active,
+		}
+		return banner, repo.UpdateBanner(ctx, tenantA, banner, "alice")
+	}
+
+	banner, err := update(1, "v2")
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if banner.Version != 2 {
+		t.Errorf("Expected version 2, got %d", banner.Version)
+	}
+
+	if _, err := update(1, "stale"); !errors.Is(err, entity.ErrorsStaleVersion) {
+		t.Error("Expected stale version error, got", err)
+	}
+
+	current, err := repo.GetBannerById(ctx, tenantA, bannerId)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if current.Version != 2 || current.Content["title"] != "v2" {
+		t.Errorf("Expected version 2 with v2, got %d with %v", current.Version, current.Content)
+	}
+
+	// revisions and the banner version stay the same numbers
+	versions, err := repo.GetBannerVersions(ctx, tenantA, bannerId)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if versions[0].Version != current.Version {
+		t.Errorf("Expected latest revision %d, got %d", current.Version, versions[0].Version)
+	}
+}
+
+func TestRepository_GetBannersPage(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db, 3, 5*time.Second)
+	ctx := context.Background()
+
+	featureId, _ := seedTenant(t, db, tenantA)
+
+	active := true
+	var created []int
+	for i := 0; i < 5; i++ {
+		var tagId int
+		err := db.QueryRow(ctx, `INSERT INTO tags (tenant_id, name) VALUES ($1, 'Tag') RETURNING tag_id;`, tenantA).Scan(&tagId)
+		if err != nil {
+			t.Fatal("Expected nil error, got", err)
+		}
+
+		bannerId, err := repo.CreateBanner(ctx, tenantA, &entity.Banner{
+			TagsId:    []int{tagId},
+			FeatureId: featureId,
+			Content:   map[string]interface{}{"n": i},
+			IsActive:  &active,
+		}, "alice")
+		if err != nil {
+			t.Fatal("Expected nil error, got", err)
+		}
+		created = append(created, bannerId)
+	}
+
+	// the same update time for all of them leaves banner_id to order the pages
+	if _, err := db.Exec(ctx, `UPDATE banners SET update_at = '2024-01-01' WHERE tenant_id = $1;`, tenantA); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	for _, sort := range []string{entity.SortBannerId, entity.SortUpdatedAt} {
+		cursor := entity.BannerCursor{Sort: sort, Desc: true}
+		var seen []int
+		for {
+			banners, err := repo.GetBannersPage(ctx, tenantA, 0, 0, cursor, 2, true)
+			if err != nil {
+				t.Fatal("Expected nil error, got", err)
+			}
+			for _, banner := range banners {
+				seen = append(seen, banner.BannerId)
+			}
+			if len(banners) < 2 {
+				break
+			}
+			cursor = cursor.After(&banners[len(banners)-1])
+		}
+
+		if len(seen) != len(created) {
+			t.Fatalf("Expected %d banners by %s, got %v", len(created), sort, seen)
+		}
+		for i, bannerId := range seen {
+			if bannerId != created[len(created)-1-i] {
+				t.Errorf("Expected banners by %s in descending id order, got %v", sort, seen)
+				break
+			}
+		}
+	}
+}
+
+func TestRepository_ConcurrentConflict(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// writers that race past the conflict check must not wait for a second
+	// connection, with two connections per writer the pool would run dry
+	config := db.Config()
+	config.MaxConns = 2
+	pool, err := pgxpool.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	t.Cleanup(pool.Close)
+	repo := NewRepository(pool, 3, 5*time.Second)
+
+	featureId, tagIds := seedTenant(t, db, tenantA)
+
+	const writers = 4
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			active := true
+			_, err := repo.CreateBanner(ctx, tenantA, &entity.Banner{
+				TagsId:    tagIds[:1],
+				FeatureId: featureId,
+				Content:   map[string]interface{}{"title": fmt.Sprint(i)},
+				IsActive:  &active,
+			}, "alice")
+			errs <- err
+		}(i)
+	}
+
+	created := 0
+	for i := 0; i < writers; i++ {
+		err := <-errs
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, entity.ErrorsConflict):
+			t.Error("Expected conflict, got", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("Expected exactly one banner to be created, got %d", created)
+	}
+}
+
+// legacySchema is what the former build/sql/initdb.sql created
+const legacySchema = `
+CREATE TABLE features (feature_id SERIAL PRIMARY KEY, name VARCHAR NOT NULL);
+CREATE TABLE banners (
+    banner_id SERIAL PRIMARY KEY,
+    content JSONB NOT NULL,
+    active BOOLEAN NOT NULL,
+    feature_id INT REFERENCES features(feature_id),
+    created_at timestamp DEFAULT now(),
+    update_at timestamp DEFAULT now()
+);
+CREATE TABLE tags (tag_id SERIAL PRIMARY KEY, name VARCHAR NOT NULL);
+CREATE TABLE banner_tags (
+    banner_id INT REFERENCES banners(banner_id),
+    tag_id INT REFERENCES tags(tag_id),
+    PRIMARY KEY (banner_id, tag_id)
+);
+INSERT INTO features (name) VALUES ('Feature1');
+INSERT INTO tags (name) VALUES ('Tag1'), ('Tag2');
+INSERT INTO banners (content, active, feature_id) VALUES ('{"title": "legacy"}', true, 1);
+INSERT INTO banner_tags (banner_id, tag_id) VALUES (1, 1), (1, 2);`
+
+func TestMigrations_LegacyUpgrade(t *testing.T) {
+	db := newTestSchema(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, legacySchema); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	migrateTestDB(t, db)
+
+	repo := NewRepository(db, 3, 5*time.Second)
+	banner, err := repo.GetBanner(ctx, tenantA, 2, 1, true, false)
+	if err != nil {
+		t.Fatal("Expected the legacy banner in tenant 1, got", err)
+	}
+	if banner.Content["title"] != "legacy" {
+		t.Errorf("Expected the legacy content, got %v", banner.Content)
+	}
+
+	versions, err := repo.GetBannerVersions(ctx, tenantA, 1)
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("Expected the first revision, got %v, %v", versions, err)
+	}
+
+	// the pairs of the legacy banner are claimed
+	active := true
+	_, err = repo.CreateBanner(ctx, tenantA, &entity.Banner{
+		TagsId:    []int{1},
+		FeatureId: 1,
+		Content:   map[string]interface{}{"title": "dup"},
+		IsActive:  &active,
+	}, "alice")
+	if !errors.Is(err, entity.ErrorsConflict) {
+		t.Error("Expected conflict, got", err)
+	}
+}
+
+func TestMigrations_LegacyDuplicatePairs(t *testing.T) {
+	db := newTestSchema(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, legacySchema); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	_, err := db.Exec(ctx, `INSERT INTO banners (content, active, feature_id) VALUES ('{"title": "twin"}', true, 1);
+							INSERT INTO banner_tags (banner_id, tag_id) VALUES (2, 1);`)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	_, err = migrator.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "feature 1, tag 1: banners {1,2}") {
+		t.Errorf("Expected the upgrade to stop on banners 1 and 2, got %v", err)
+	}
+}
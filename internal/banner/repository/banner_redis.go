@@ -7,58 +7,189 @@ import (
 	"time"
 
 	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ctxutil"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/metrics"
 	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/DmitriyKomarovCoder/banner-api/internal/banner/repository")
+
 type cache struct {
-	db *redis.Client
+	db      *redis.Client
+	timeout time.Duration
+	metrics *metrics.Metrics
 }
 
-func NewCache(db *redis.Client) *cache {
+// NewCache creates redis cache, timeout bounds every call the same way
+// the repository's queryTimeout does.
+func NewCache(db *redis.Client, timeout time.Duration, m *metrics.Metrics) *cache {
 	return &cache{
-		db: db,
+		db:      db,
+		timeout: timeout,
+		metrics: m,
 	}
 }
 
 const (
 	getCacheLayerMSG = "Get cache layer: %w"
 	setCacheLayerMSG = "Set cache layer: %w"
+	delCacheLayerMSG = "Delete cache layer: %w"
 	//==================
 	TTL = 5 * time.Minute
 )
 
-func (r *cache) Set(tagID int, featureID int, content interface{}) error {
-	key := fmt.Sprintf("%d:%d", tagID, featureID)
+// cachedBanner is what the cache keeps, the id lets callers tell which banner
+// was served without going to postgres. The content stays serialized and keeps
+// its ETag, hits are written out as they are.
+type cachedBanner struct {
+	BannerId  int             `json:"banner_id"`
+	FeatureId int             `json:"feature_id"`
+	Content   json.RawMessage `json:"content"`
+	ETag      string          `json:"etag"`
+	EndsAt    *time.Time      `json:"ends_at,omitempty"`
+}
+
+// Set caches the banner for TTL, or until its EndsAt when that comes first. A
+// banner that has already expired is not stored at all.
+func (r *cache) Set(ctx context.Context, tenantId, tagID int, featureID int, banner *entity.UserBanner) error {
+	return r.set(ctx, cacheKey(tenantId, tagID, featureID), banner)
+}
+
+func (r *cache) Get(ctx context.Context, tenantId, tagID int, featureID int) (*entity.UserBanner, error) {
+	return r.get(ctx, cacheKey(tenantId, tagID, featureID))
+}
+
+// SetById caches a banner served by an experiment variant, just like Set never
+// past its EndsAt.
+func (r *cache) SetById(ctx context.Context, tenantId int, banner *entity.UserBanner) error {
+	return r.set(ctx, bannerCacheKey(tenantId, banner.BannerId), banner)
+}
+
+func (r *cache) GetById(ctx context.Context, tenantId, bannerId int) (*entity.UserBanner, error) {
+	return r.get(ctx, bannerCacheKey(tenantId, bannerId))
+}
+
+// Delete drops the banner from the pairs of its feature and tags and from the
+// entry of experiment variants. The banner has to carry the feature and tags
+// it was cached under, after an update those are the old ones.
+func (r *cache) Delete(ctx context.Context, tenantId int, banner *entity.Banner) error {
+	keys := make([]string, 0, len(banner.TagsId)+1)
+	keys = append(keys, bannerCacheKey(tenantId, banner.BannerId))
+	for _, tagId := range banner.TagsId {
+		keys = append(keys, cacheKey(tenantId, tagId, banner.FeatureId))
+	}
+
+	ctx, span := tracer.Start(ctx, "cache.Delete", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.StringSlice("cache.keys", keys)))
+	defer span.End()
+
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if err := r.db.Del(ctx, keys...).Err(); err != nil {
+		r.metrics.ObserveCache(metrics.CacheDelete, metrics.CacheError)
+		return fmt.Errorf(delCacheLayerMSG, ctxutil.Err(ctx, err))
+	}
+
+	r.metrics.ObserveCache(metrics.CacheDelete, metrics.CacheOK)
+	return nil
+}
+
+func (r *cache) set(ctx context.Context, key string, banner *entity.UserBanner) error {
+	ttl := TTL
+	if banner.EndsAt != nil {
+		if untilEnd := time.Until(*banner.EndsAt); untilEnd < ttl {
+			ttl = untilEnd
+		}
+	}
+	// go-redis treats a zero or negative expiration as keep forever
+	if ttl <= 0 {
+		return nil
+	}
+
+	ctx, span := tracer.Start(ctx, "cache.Set", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("cache.key", key)))
+	defer span.End()
 
-	jsonData, err := json.Marshal(content)
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	jsonData, err := json.Marshal(cachedBanner{
+		BannerId:  banner.BannerId,
+		FeatureId: banner.FeatureId,
+		Content:   banner.Content,
+		ETag:      banner.ETag,
+		EndsAt:    banner.EndsAt,
+	})
 	if err != nil {
+		r.metrics.ObserveCache(metrics.CacheSet, metrics.CacheError)
 		return fmt.Errorf("failed to marshal data: %v", err)
 	}
 
-	err = r.db.Set(context.Background(), key, jsonData, TTL).Err()
+	err = r.db.Set(ctx, key, jsonData, ttl).Err()
 	if err != nil {
-		return fmt.Errorf("failed to set data in cache: %v", err)
+		r.metrics.ObserveCache(metrics.CacheSet, metrics.CacheError)
+		return fmt.Errorf("failed to set data in cache: %w", ctxutil.Err(ctx, err))
 	}
 
+	r.metrics.ObserveCache(metrics.CacheSet, metrics.CacheOK)
 	return nil
 }
 
-func (r *cache) Get(tagID int, featureID int) (interface{}, error) {
-	key := fmt.Sprintf("%d:%d", tagID, featureID)
+func (r *cache) get(ctx context.Context, key string) (*entity.UserBanner, error) {
+	ctx, span := tracer.Start(ctx, "cache.Get", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("cache.key", key)))
+	defer span.End()
+
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.timeout)
+	defer cancel()
 
-	content, err := r.db.Get(context.Background(), key).Result()
+	content, err := r.db.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
+			span.SetAttributes(attribute.Bool("cache.hit", false))
+			r.metrics.ObserveCache(metrics.CacheGet, metrics.CacheMiss)
 			return nil, fmt.Errorf(getCacheLayerMSG, entity.ErrorsNotFound)
 		}
-		return nil, fmt.Errorf(getCacheLayerMSG, err)
+		r.metrics.ObserveCache(metrics.CacheGet, metrics.CacheError)
+		return nil, fmt.Errorf(getCacheLayerMSG, ctxutil.Err(ctx, err))
 	}
 
-	var data map[string]interface{}
+	var data cachedBanner
 	err = json.Unmarshal([]byte(content), &data)
 	if err != nil {
+		r.metrics.ObserveCache(metrics.CacheGet, metrics.CacheError)
 		return nil, fmt.Errorf("failed to unmarshal data: %v", err)
 	}
 
-	return data, nil
+	// entries written before the ids and the ETag were cached lack them, they
+	// expire within TTL and are refilled meanwhile
+	if data.BannerId == 0 || data.FeatureId == 0 || data.ETag == "" {
+		span.SetAttributes(attribute.Bool("cache.hit", false))
+		r.metrics.ObserveCache(metrics.CacheGet, metrics.CacheMiss)
+		return nil, fmt.Errorf(getCacheLayerMSG, entity.ErrorsNotFound)
+	}
+
+	span.SetAttributes(attribute.Bool("cache.hit", true))
+	r.metrics.ObserveCache(metrics.CacheGet, metrics.CacheHit)
+	return &entity.UserBanner{
+		BannerId:  data.BannerId,
+		FeatureId: data.FeatureId,
+		Content:   data.Content,
+		ETag:      data.ETag,
+		EndsAt:    data.EndsAt,
+	}, nil
+}
+
+// cacheKey is prefixed with the tenant, so equal tag and feature ids of
+// different tenants never share an entry
+func cacheKey(tenantId, tagID, featureID int) string {
+	return fmt.Sprintf("%d:%d:%d", tenantId, tagID, featureID)
+}
+
+func bannerCacheKey(tenantId, bannerId int) string {
+	return fmt.Sprintf("%d:banner:%d", tenantId, bannerId)
 }
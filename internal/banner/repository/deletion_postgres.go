@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ctxutil"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	createDeletionJobMSG = "CreateDeletionJob repository layer: %w"
+	getDeletionJobMSG    = "GetDeletionJob repository layer: %w"
+	processDeletionMSG   = "ProcessDeletionBatch repository layer: %w"
+	failDeletionJobMSG   = "FailDeletionJob repository layer: %w"
+	// =============================
+	createDeletionJobSQL = `INSERT INTO deletion_requests (tenant_id, feature_id, tag_id, total)
+							SELECT $1, NULLIF($2, 0), NULLIF($3, 0), COUNT(*)
+							FROM banners b
+							WHERE b.tenant_id = $1
+							AND ($2 = 0 OR b.feature_id = $2)
+							AND ($3 = 0 OR EXISTS (SELECT 1 FROM banner_tags bt WHERE bt.banner_id = b.banner_id AND bt.tag_id = $3))
+							RETURNING request_id;`
+
+	getDeletionJobSQL = `SELECT request_id, tenant_id, COALESCE(feature_id, 0), COALESCE(tag_id, 0), status, total, deleted,
+							attempts, next_attempt_at, COALESCE(last_error, ''), created_at, update_at
+						 FROM deletion_requests
+						 WHERE tenant_id = $1 AND request_id = $2;`
+
+	// SKIP LOCKED lets several replicas drain different jobs at the same time,
+	// a job waiting out its backoff lets the jobs behind it go first
+	nextDeletionJobSQL = `SELECT request_id, tenant_id, COALESCE(feature_id, 0), COALESCE(tag_id, 0), status, attempts
+						  FROM deletion_requests
+						  WHERE status NOT IN ('done', 'dead') AND next_attempt_at <= now()
+						  ORDER BY request_id
+						  LIMIT 1
+						  FOR UPDATE SKIP LOCKED;`
+
+	deletionBatchSQL = `SELECT b.banner_id, b.feature_id, ARRAY(SELECT bt.tag_id FROM banner_tags bt WHERE bt.banner_id = b.banner_id)
+						FROM banners b
+						WHERE b.tenant_id = $1
+						AND ($2 = 0 OR b.feature_id = $2)
+						AND ($3 = 0 OR EXISTS (SELECT 1 FROM banner_tags bt WHERE bt.banner_id = b.banner_id AND bt.tag_id = $3))
+						LIMIT $4;`
+
+	rmBatchTagsSQL        = `DELETE FROM banner_tags WHERE tenant_id = $1 AND banner_id = ANY($2);`
+	rmBatchFeatureTagsSQL = `DELETE FROM banner_feature_tags WHERE tenant_id = $1 AND banner_id = ANY($2);`
+	rmBatchVersionsSQL    = `DELETE FROM banner_versions WHERE tenant_id = $1 AND banner_id = ANY($2);`
+	rmBatchBannersSQL     = `DELETE FROM banners WHERE tenant_id = $1 AND banner_id = ANY($2);`
+
+	// a batch that went through ends the run of failures
+	updDeletionJobSQL = `UPDATE deletion_requests
+						 SET deleted = deleted + $2, status = $3, attempts = 0, last_error = NULL, update_at = now()
+						 WHERE request_id = $1
+						 RETURNING request_id, tenant_id, COALESCE(feature_id, 0), COALESCE(tag_id, 0), status, total, deleted,
+							attempts, next_attempt_at, COALESCE(last_error, ''), created_at, update_at;`
+
+	// the failure is only counted once, when another replica picked the job and
+	// failed in the meantime attempts has moved on
+	failDeletionJobSQL = `UPDATE deletion_requests
+						  SET attempts = $2, status = $3, next_attempt_at = $4, last_error = $5, update_at = now()
+						  WHERE request_id = $1 AND attempts = $2 - 1;`
+)
+
+func (r *repository) CreateDeletionJob(ctx context.Context, tenantId, featureId, tagId int) (int, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var jobId int
+	err := r.db.QueryRow(ctx, createDeletionJobSQL, tenantId, featureId, tagId).Scan(&jobId)
+	if err != nil {
+		return 0, fmt.Errorf(createDeletionJobMSG, err)
+	}
+	return jobId, nil
+}
+
+func (r *repository) GetDeletionJob(ctx context.Context, tenantId, jobId int) (*entity.DeletionJob, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var job entity.DeletionJob
+	err := scanDeletionJob(r.db.QueryRow(ctx, getDeletionJobSQL, tenantId, jobId), &job)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(getDeletionJobMSG, entity.ErrorsNotFound)
+		}
+		return nil, fmt.Errorf(getDeletionJobMSG, err)
+	}
+	return &job, nil
+}
+
+// ProcessDeletionBatch picks the oldest unfinished job that is due and deletes up
+// to batchSize of its banners in one transaction. It returns ErrorsNotFound when
+// no job is due and a DeletionBatchError when the batch failed.
+func (r *repository) ProcessDeletionBatch(ctx context.Context, batchSize int) (*entity.DeletionJob, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf(processDeletionMSG, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var job entity.DeletionJob
+	err = tx.QueryRow(ctx, nextDeletionJobSQL).Scan(&job.JobId, &job.TenantId, &job.FeatureId, &job.TagId, &job.Status, &job.Attempts)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(processDeletionMSG, entity.ErrorsNotFound)
+		}
+		return nil, fmt.Errorf(processDeletionMSG, err)
+	}
+
+	done, err := r.deleteBatch(ctx, tx, &job, batchSize)
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(processDeletionMSG, &entity.DeletionBatchError{Job: job, Err: err})
+	}
+
+	return done, nil
+}
+
+// deleteBatch deletes the next batchSize banners of the job inside tx and
+// returns the job as it is after the batch.
+func (r *repository) deleteBatch(ctx context.Context, tx pgx.Tx, job *entity.DeletionJob, batchSize int) (*entity.DeletionJob, error) {
+	rows, err := tx.Query(ctx, deletionBatchSQL, job.TenantId, job.FeatureId, job.TagId, batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	bannerIds := []int{}
+	removed := []entity.Banner{}
+	for rows.Next() {
+		var banner entity.Banner
+		if err := rows.Scan(&banner.BannerId, &banner.FeatureId, &banner.TagsId); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		bannerIds = append(bannerIds, banner.BannerId)
+		removed = append(removed, banner)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := writeOutbox(ctx, tx, job.TenantId, entity.BannerDeleted, bannerIds); err != nil {
+		return nil, err
+	}
+
+	for _, query := range []string{rmBatchTagsSQL, rmBatchFeatureTagsSQL, rmBatchVersionsSQL, rmBatchBannersSQL} {
+		if _, err := tx.Exec(ctx, query, job.TenantId, bannerIds); err != nil {
+			return nil, err
+		}
+	}
+
+	status := entity.DeletionStatusRunning
+	if len(bannerIds) < batchSize {
+		status = entity.DeletionStatusDone
+	}
+
+	var done entity.DeletionJob
+	if err := scanDeletionJob(tx.QueryRow(ctx, updDeletionJobSQL, job.JobId, len(bannerIds), status), &done); err != nil {
+		return nil, err
+	}
+	done.Removed = removed
+	return &done, nil
+}
+
+// FailDeletionJob saves the attempts, status and next attempt of a job whose
+// batch failed.
+func (r *repository) FailDeletionJob(ctx context.Context, job *entity.DeletionJob) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.db.Exec(ctx, failDeletionJobSQL, job.JobId, job.Attempts, job.Status, job.NextAttempt, job.LastError)
+	if err != nil {
+		return fmt.Errorf(failDeletionJobMSG, err)
+	}
+	return nil
+}
+
+func scanDeletionJob(row pgx.Row, job *entity.DeletionJob) error {
+	return row.Scan(
+		&job.JobId,
+		&job.TenantId,
+		&job.FeatureId,
+		&job.TagId,
+		&job.Status,
+		&job.Total,
+		&job.Deleted,
+		&job.Attempts,
+		&job.NextAttempt,
+		&job.LastError,
+		&job.CreatedDate,
+		&job.UpdateDate,
+	)
+}
@@ -1,87 +1,228 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/DmitriyKomarovCoder/banner-api/internal/banner"
 	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/schema"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/DmitriyKomarovCoder/banner-api/internal/banner/usecase")
+
 type Usecase struct {
 	bannerRepo  banner.Repository
 	bannerCache banner.Cashe
+	experiments banner.Experiments
 }
 
-func NewUsecase(br banner.Repository, bc banner.Cashe) *Usecase {
+func NewUsecase(br banner.Repository, bc banner.Cashe, ex banner.Experiments) *Usecase {
 	return &Usecase{
 		bannerRepo:  br,
 		bannerCache: bc,
+		experiments: ex,
 	}
 }
 
 const (
-	getBannerMSG    = "GetBanner usecase layer: %w"
-	getBannersMSG   = "GetBanners usecase layer: %w"
-	createBannerMSG = "CreateBanner usecase layer: %w"
-	updateBannerMSG = "UpdateBanner usecase layer: %w"
-	deleteBannerMSG = "DeleteBanner usecase layer: %w"
+	getBannerMSG      = "GetBanner usecase layer: %w"
+	getBannersMSG     = "GetBanners usecase layer: %w"
+	getBannersPageMSG = "GetBannersPage usecase layer: %w"
+	createBannerMSG   = "CreateBanner usecase layer: %w"
+	updateBannerMSG   = "UpdateBanner usecase layer: %w"
+	deleteBannerMSG   = "DeleteBanner usecase layer: %w"
+	getVersionsMSG    = "GetBannerVersions usecase layer: %w"
+	restoreMSG        = "RestoreBannerVersion usecase layer: %w"
+	deleteBannersMSG  = "DeleteBanners usecase layer: %w"
+	getDeletionJobMSG = "GetDeletionJob usecase layer: %w"
+	processBatchMSG   = "ProcessDeletionBatch usecase layer: %w"
+	exportBannersMSG  = "ExportBanners usecase layer: %w"
+	importBannersMSG  = "ImportBanners usecase layer: %w"
 )
 
-func (u *Usecase) GetBanner(tagId, featureId int, useLastRevision, isAdmin bool) (interface{}, error) {
-	var content interface{}
-	var err error
-	var active bool
+// GetBanner returns the content the user gets for the feature and tag. While an
+// experiment is running on them, users with a userId get the banner of their
+// variant instead, anonymous ones still get the regular banner.
+func (u *Usecase) GetBanner(ctx context.Context, principal *auth.Principal, tagId, featureId int, useLastRevision bool, userId string) (*entity.UserBanner, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetBanner")
+	defer span.End()
+
+	if userId != "" {
+		assignment, err := u.experiments.Assign(ctx, principal.TenantId, tagId, featureId, userId)
+		if err != nil {
+			return nil, fmt.Errorf(getBannerMSG, err)
+		}
+
+		if assignment != nil {
+			userBanner, err := u.variantBanner(ctx, principal, assignment.BannerId, featureId, useLastRevision)
+			if err != nil {
+				return nil, fmt.Errorf(getBannerMSG, err)
+			}
+			// a variant the caller may not see falls back to the regular banner
+			// and is not counted for the experiment
+			if userBanner != nil {
+				u.experiments.RecordAssignment(ctx, principal.TenantId, assignment, userId)
+				userBanner.ExperimentId, userBanner.Variant = assignment.ExperimentId, assignment.Variant
+				return userBanner, nil
+			}
+		}
+	}
+
+	showInactive := principal.Can(auth.PermBannerReadInactive)
 	if useLastRevision {
-		content, active, err = u.bannerRepo.GetBanner(tagId, featureId, useLastRevision, isAdmin)
+		banner, err := u.bannerRepo.GetBanner(ctx, principal.TenantId, tagId, featureId, useLastRevision, showInactive)
 		if err != nil {
 			return nil, fmt.Errorf(getBannerMSG, err)
 		}
-		return content, nil
+
+		userBanner, err := entity.NewUserBanner(banner)
+		if err != nil {
+			return nil, fmt.Errorf(getBannerMSG, err)
+		}
+		return userBanner, nil
 	}
 
-	content, err = u.bannerCache.Get(tagId, featureId)
+	cached, err := u.bannerCache.Get(ctx, principal.TenantId, tagId, featureId)
 	if err != nil {
 		if errors.Is(err, entity.ErrorsNotFound) {
-			content, active, err = u.bannerRepo.GetBanner(tagId, featureId, useLastRevision, isAdmin)
+			banner, err := u.bannerRepo.GetBanner(ctx, principal.TenantId, tagId, featureId, useLastRevision, showInactive)
+			if err != nil {
+				return nil, fmt.Errorf(getBannerMSG, err)
+			}
+
+			userBanner, err := entity.NewUserBanner(banner)
 			if err != nil {
 				return nil, fmt.Errorf(getBannerMSG, err)
 			}
-			if active {
-				err = u.bannerCache.Set(tagId, featureId, content)
+			// the cache is shared by all callers, so a banner an admin sees outside
+			// of its window must not end up there
+			if banner.Live(time.Now()) {
+				err = u.bannerCache.Set(ctx, principal.TenantId, tagId, featureId, userBanner)
 			}
 
 			if err != nil {
 				return nil, fmt.Errorf(getBannerMSG, err)
 			}
+			return userBanner, nil
 		} else {
 			return nil, fmt.Errorf(getBannerMSG, err)
 		}
 	}
 
-	return content, nil
+	return cached, nil
+}
+
+// variantBanner reads the banner of an experiment variant. Like any other banner
+// it is only served while it is live, unless the caller may read inactive
+// banners, and only while it still belongs to the feature of the experiment;
+// nil means the caller doesn't get it. Only live banners are cached and never
+// past their ends_at, so a cached one can be served as is.
+func (u *Usecase) variantBanner(ctx context.Context, principal *auth.Principal, bannerId, featureId int, useLastRevision bool) (*entity.UserBanner, error) {
+	if !useLastRevision {
+		userBanner, err := u.bannerCache.GetById(ctx, principal.TenantId, bannerId)
+		if err == nil {
+			if userBanner.FeatureId != featureId {
+				return nil, nil
+			}
+			return userBanner, nil
+		}
+		if !errors.Is(err, entity.ErrorsNotFound) {
+			return nil, err
+		}
+	}
+
+	banner, err := u.bannerRepo.GetBannerById(ctx, principal.TenantId, bannerId)
+	if err != nil {
+		return nil, err
+	}
+
+	// the banner may have been moved to another feature since the experiment started
+	if banner.FeatureId != featureId {
+		return nil, nil
+	}
+
+	live := banner.Live(time.Now())
+	if !live && !principal.Can(auth.PermBannerReadInactive) {
+		return nil, nil
+	}
+
+	userBanner, err := entity.NewUserBanner(banner)
+	if err != nil {
+		return nil, err
+	}
+
+	if live {
+		if err := u.bannerCache.SetById(ctx, principal.TenantId, userBanner); err != nil {
+			return nil, err
+		}
+	}
+	return userBanner, nil
 }
 
-func (u *Usecase) GetBanners(tagId, featureId int, limit, offset int, isAdmin bool) ([]entity.Banner, error) {
-	banners, err := u.bannerRepo.GetBanners(tagId, featureId, limit, offset, isAdmin)
+func (u *Usecase) GetBanners(ctx context.Context, principal *auth.Principal, tagId, featureId int, limit, offset int) ([]entity.Banner, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetBanners")
+	defer span.End()
+
+	banners, err := u.bannerRepo.GetBanners(ctx, principal.TenantId, tagId, featureId, limit, offset, principal.Can(auth.PermBannerReadInactive))
 	if err != nil {
 		return nil, fmt.Errorf(getBannersMSG, err)
 	}
 	return banners, nil
 }
 
-func (u *Usecase) CreateBanner(createBanner *entity.Banner) (int, error) {
-	flag, err := u.bannerRepo.CheckIfTagsExist(createBanner.TagsId)
+// GetBannersPage returns up to limit banners after the cursor. One banner more
+// is read to tell whether another page follows.
+func (u *Usecase) GetBannersPage(ctx context.Context, principal *auth.Principal, tagId, featureId int, cursor entity.BannerCursor, limit int) (*entity.BannerPage, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetBannersPage")
+	defer span.End()
+
+	banners, err := u.bannerRepo.GetBannersPage(ctx, principal.TenantId, tagId, featureId, cursor, limit+1, principal.Can(auth.PermBannerReadInactive))
+	if err != nil {
+		return nil, fmt.Errorf(getBannersPageMSG, err)
+	}
+
+	page := &entity.BannerPage{Banners: banners}
+	if len(banners) > limit {
+		page.Banners = banners[:limit]
+		next := cursor.After(&page.Banners[limit-1])
+		page.Next = &next
+	}
+	return page, nil
+}
+
+func (u *Usecase) CreateBanner(ctx context.Context, principal *auth.Principal, createBanner *entity.Banner) (int, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.CreateBanner")
+	defer span.End()
+
+	if err := checkPublish(principal, createBanner); err != nil {
+		return 0, fmt.Errorf(createBannerMSG, err)
+	}
+
+	if err := checkSchedule(createBanner); err != nil {
+		return 0, fmt.Errorf(createBannerMSG, err)
+	}
+
+	flag, err := u.bannerRepo.CheckIfTagsExist(ctx, principal.TenantId, createBanner.TagsId)
 	if !flag || err != nil {
 		return 0, fmt.Errorf(createBannerMSG, err)
 	}
 
-	flag, err = u.bannerRepo.CheckIfFeatureIdExist(createBanner.FeatureId)
+	flag, err = u.bannerRepo.CheckIfFeatureIdExist(ctx, principal.TenantId, createBanner.FeatureId)
 	if !flag || err != nil {
 		return 0, fmt.Errorf(createBannerMSG, err)
 	}
 
-	bannerId, err := u.bannerRepo.CreateBanner(createBanner)
+	if err := u.validateContent(ctx, principal.TenantId, createBanner); err != nil {
+		return 0, fmt.Errorf(createBannerMSG, err)
+	}
+
+	bannerId, err := u.bannerRepo.CreateBanner(ctx, principal.TenantId, createBanner, principal.Subject)
 	if err != nil {
 		return 0, fmt.Errorf(createBannerMSG, err)
 	}
@@ -89,22 +230,35 @@ func (u *Usecase) CreateBanner(createBanner *entity.Banner) (int, error) {
 	return bannerId, nil
 }
 
-func (u *Usecase) UpdateBanner(updBanner *entity.Banner) error {
-	currentBanner, err := u.bannerRepo.GetBannerById(updBanner.BannerId)
+func (u *Usecase) UpdateBanner(ctx context.Context, principal *auth.Principal, updBanner *entity.Banner) error {
+	ctx, span := tracer.Start(ctx, "Usecase.UpdateBanner")
+	defer span.End()
+
+	currentBanner, err := u.bannerRepo.GetBannerById(ctx, principal.TenantId, updBanner.BannerId)
 	if err != nil {
 		return fmt.Errorf(updateBannerMSG, err)
 	}
 
+	// the repository checks it again under the row lock, this only saves the
+	// work for an update that can't succeed
+	if updBanner.Version != 0 && updBanner.Version != currentBanner.Version {
+		return fmt.Errorf(updateBannerMSG, entity.ErrorsStaleVersion)
+	}
+
+	// stored banners are not rechecked when a schema changes, so only a new
+	// content or feature has to match it
+	contentChanged := updBanner.Content != nil || updBanner.FeatureId != 0
+
 	var flag bool
 	if len(updBanner.TagsId) != 0 {
-		flag, err = u.bannerRepo.CheckIfTagsExist(updBanner.TagsId)
+		flag, err = u.bannerRepo.CheckIfTagsExist(ctx, principal.TenantId, updBanner.TagsId)
 		if !flag || err != nil {
 			return fmt.Errorf(updateBannerMSG, err)
 		}
 	}
 
 	if updBanner.FeatureId != 0 {
-		flag, err = u.bannerRepo.CheckIfFeatureIdExist(updBanner.FeatureId)
+		flag, err = u.bannerRepo.CheckIfFeatureIdExist(ctx, principal.TenantId, updBanner.FeatureId)
 		if !flag || err != nil {
 			return fmt.Errorf(updateBannerMSG, err)
 		}
@@ -126,16 +280,368 @@ func (u *Usecase) UpdateBanner(updBanner *entity.Banner) error {
 		updBanner.IsActive = currentBanner.IsActive
 	}
 
-	if err := u.bannerRepo.UpdateBanner(updBanner); err != nil {
+	if updBanner.StartsAt == nil {
+		updBanner.StartsAt = currentBanner.StartsAt
+	}
+
+	if updBanner.EndsAt == nil {
+		updBanner.EndsAt = currentBanner.EndsAt
+	}
+
+	if err := checkPublish(principal, updBanner); err != nil {
 		return fmt.Errorf(updateBannerMSG, err)
 	}
 
+	if err := checkSchedule(updBanner); err != nil {
+		return fmt.Errorf(updateBannerMSG, err)
+	}
+
+	if contentChanged {
+		if err := u.validateContent(ctx, principal.TenantId, updBanner); err != nil {
+			return fmt.Errorf(updateBannerMSG, err)
+		}
+	}
+
+	if err := u.bannerRepo.UpdateBanner(ctx, principal.TenantId, updBanner, principal.Subject); err != nil {
+		return fmt.Errorf(updateBannerMSG, err)
+	}
+
+	u.evict(ctx, principal.TenantId, currentBanner, updBanner)
+	return nil
+}
+
+func (r *Usecase) DeleteBanner(ctx context.Context, principal *auth.Principal, bannerId int) error {
+	ctx, span := tracer.Start(ctx, "Usecase.DeleteBanner")
+	defer span.End()
+
+	// the feature and tags say which cache entries the banner is under
+	currentBanner, err := r.bannerRepo.GetBannerById(ctx, principal.TenantId, bannerId)
+	if err != nil {
+		return fmt.Errorf(deleteBannerMSG, err)
+	}
+
+	if err := r.bannerRepo.DeleteBanner(ctx, principal.TenantId, bannerId); err != nil {
+		return fmt.Errorf(deleteBannerMSG, err)
+	}
+
+	r.evict(ctx, principal.TenantId, currentBanner)
+	return nil
+}
+
+func (u *Usecase) GetBannerVersions(ctx context.Context, principal *auth.Principal, bannerId int) ([]entity.BannerVersion, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetBannerVersions")
+	defer span.End()
+
+	if _, err := u.bannerRepo.GetBannerById(ctx, principal.TenantId, bannerId); err != nil {
+		return nil, fmt.Errorf(getVersionsMSG, err)
+	}
+
+	versions, err := u.bannerRepo.GetBannerVersions(ctx, principal.TenantId, bannerId)
+	if err != nil {
+		return nil, fmt.Errorf(getVersionsMSG, err)
+	}
+	return versions, nil
+}
+
+// RestoreBannerVersion rolls the banner back to the given revision. The restored
+// state is written as a new revision, so the history itself is never rewritten.
+func (u *Usecase) RestoreBannerVersion(ctx context.Context, principal *auth.Principal, bannerId, version int) error {
+	ctx, span := tracer.Start(ctx, "Usecase.RestoreBannerVersion")
+	defer span.End()
+
+	currentBanner, err := u.bannerRepo.GetBannerById(ctx, principal.TenantId, bannerId)
+	if err != nil {
+		return fmt.Errorf(restoreMSG, err)
+	}
+
+	bannerVersion, err := u.bannerRepo.GetBannerVersion(ctx, principal.TenantId, bannerId, version)
+	if err != nil {
+		return fmt.Errorf(restoreMSG, err)
+	}
+
+	restored := entity.Banner{
+		BannerId:  bannerVersion.BannerId,
+		TagsId:    bannerVersion.TagsId,
+		FeatureId: bannerVersion.FeatureId,
+		Content:   bannerVersion.Content,
+		IsActive:  bannerVersion.IsActive,
+		StartsAt:  bannerVersion.StartsAt,
+		EndsAt:    bannerVersion.EndsAt,
+	}
+
+	if err := checkPublish(principal, &restored); err != nil {
+		return fmt.Errorf(restoreMSG, err)
+	}
+
+	// the feature and tags of an old revision may have been deleted since
+	tagsExist, err := u.bannerRepo.CheckIfTagsExist(ctx, principal.TenantId, restored.TagsId)
+	if err != nil {
+		return fmt.Errorf(restoreMSG, err)
+	}
+	featureExists, err := u.bannerRepo.CheckIfFeatureIdExist(ctx, principal.TenantId, restored.FeatureId)
+	if err != nil {
+		return fmt.Errorf(restoreMSG, err)
+	}
+	if !tagsExist || !featureExists {
+		return fmt.Errorf(restoreMSG, entity.ErrorsRevisionGone)
+	}
+
+	// the schema may have changed since the revision was written
+	if err := u.validateContent(ctx, principal.TenantId, &restored); err != nil {
+		return fmt.Errorf(restoreMSG, err)
+	}
+
+	if err := u.bannerRepo.UpdateBanner(ctx, principal.TenantId, &restored, principal.Subject); err != nil {
+		return fmt.Errorf(restoreMSG, err)
+	}
+
+	u.evict(ctx, principal.TenantId, currentBanner, &restored)
+	return nil
+}
+
+// DeleteBanners queues removal of every banner matching the feature and/or tag
+// and returns the id of the job that tracks it.
+func (u *Usecase) DeleteBanners(ctx context.Context, principal *auth.Principal, featureId, tagId int) (int, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.DeleteBanners")
+	defer span.End()
+
+	jobId, err := u.bannerRepo.CreateDeletionJob(ctx, principal.TenantId, featureId, tagId)
+	if err != nil {
+		return 0, fmt.Errorf(deleteBannersMSG, err)
+	}
+	return jobId, nil
+}
+
+func (u *Usecase) GetDeletionJob(ctx context.Context, principal *auth.Principal, jobId int) (*entity.DeletionJob, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetDeletionJob")
+	defer span.End()
+
+	job, err := u.bannerRepo.GetDeletionJob(ctx, principal.TenantId, jobId)
+	if err != nil {
+		return nil, fmt.Errorf(getDeletionJobMSG, err)
+	}
+	return job, nil
+}
+
+func (u *Usecase) ProcessDeletionBatch(ctx context.Context, batchSize int, retry entity.RetryPolicy) (*entity.DeletionJob, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.ProcessDeletionBatch")
+	defer span.End()
+
+	job, err := u.bannerRepo.ProcessDeletionBatch(ctx, batchSize)
+	if err != nil {
+		// a batch cut short by the shutdown is not the fault of the job
+		var failed *entity.DeletionBatchError
+		if errors.As(err, &failed) && ctx.Err() == nil {
+			failDeletion(&failed.Job, retry, failed.Err, time.Now())
+			if err := u.bannerRepo.FailDeletionJob(ctx, &failed.Job); err != nil {
+				span.RecordError(err)
+			}
+		}
+		return nil, fmt.Errorf(processBatchMSG, err)
+	}
+
+	for i := range job.Removed {
+		u.evict(ctx, job.TenantId, &job.Removed[i])
+	}
+	return job, nil
+}
+
+// failDeletion counts a failed batch of the job. It is retried after a backoff,
+// after retry.MaxAttempts failures in a row it is dead.
+func failDeletion(job *entity.DeletionJob, retry entity.RetryPolicy, batchErr error, now time.Time) {
+	job.Attempts++
+	job.LastError = batchErr.Error()
+
+	if job.Attempts >= retry.MaxAttempts {
+		job.Status = entity.DeletionStatusDead
+		job.NextAttempt = now
+		return
+	}
+	job.NextAttempt = now.Add(retry.Backoff(job.Attempts))
+}
+
+// evict drops the cached entries of banners that were just changed or deleted,
+// both the old and the new state of an update are passed. The write is already
+// done, so a failure only leaves the stale entry until its TTL and is not
+// returned.
+func (u *Usecase) evict(ctx context.Context, tenantId int, banners ...*entity.Banner) {
+	for _, banner := range banners {
+		if err := u.bannerCache.Delete(ctx, tenantId, banner); err != nil {
+			trace.SpanFromContext(ctx).RecordError(err)
+		}
+	}
+}
+
+// ExportBanners streams the banners of the tenant to fn, inactive ones included.
+func (u *Usecase) ExportBanners(ctx context.Context, principal *auth.Principal, tagId, featureId int, fn func(*entity.Banner) error) error {
+	ctx, span := tracer.Start(ctx, "Usecase.ExportBanners")
+	defer span.End()
+
+	if err := u.bannerRepo.ExportBanners(ctx, principal.TenantId, tagId, featureId, fn); err != nil {
+		return fmt.Errorf(exportBannersMSG, err)
+	}
+	return nil
+}
+
+// ImportBanners checks every line the same way CreateBanner does and writes the
+// valid ones in one transaction. Every line gets a result, lines that fail don't
+// stop the others from being checked, but any failure rolls the import back.
+// Errors of the data itself end up in the report, only a broken database or an
+// aborted request fail the whole call.
+func (u *Usecase) ImportBanners(ctx context.Context, principal *auth.Principal, lines []entity.ImportLine, dryRun bool) (*entity.ImportReport, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.ImportBanners")
+	defer span.End()
+
+	report := &entity.ImportReport{DryRun: dryRun, Results: make([]entity.ImportResult, len(lines))}
+	banners := make([]entity.Banner, 0, len(lines))
+	// positions of banners in lines
+	positions := make([]int, 0, len(lines))
+	keys := make(map[string]int)
+
+	for i := range lines {
+		line := &lines[i]
+		report.Results[i] = entity.ImportResult{Line: line.Line, ExternalKey: line.Banner.ExternalKey}
+
+		err := line.Err
+		if err == nil {
+			err = u.checkImport(ctx, principal, &line.Banner, keys, line.Line)
+		}
+
+		if err != nil {
+			if !isLineError(err) {
+				return nil, fmt.Errorf(importBannersMSG, err)
+			}
+			report.Results[i].Action = entity.ImportFailed
+			report.Results[i].Err = err
+			continue
+		}
+
+		banners = append(banners, line.Banner)
+		positions = append(positions, i)
+	}
+
+	rollback := dryRun || len(banners) < len(lines)
+	results, err := u.bannerRepo.ImportBanners(ctx, principal.TenantId, banners, principal.Subject, rollback)
+	if err != nil {
+		return nil, fmt.Errorf(importBannersMSG, err)
+	}
+
+	report.Committed = !rollback
+	for j, result := range results {
+		report.Results[positions[j]].BannerId = result.BannerId
+		report.Results[positions[j]].Action = result.Action
+		report.Results[positions[j]].Err = result.Err
+		if result.Err != nil {
+			report.Committed = false
+		}
+	}
+
+	if report.Committed {
+		for j, result := range results {
+			if result.Action == entity.ImportUpdated {
+				u.evict(ctx, principal.TenantId, result.Previous, &banners[j])
+			}
+		}
+	}
+
+	// banners created by a rolled back import don't exist
+	if !report.Committed {
+		for i := range report.Results {
+			if report.Results[i].Action == entity.ImportCreated {
+				report.Results[i].BannerId = 0
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// checkImport runs the checks of CreateBanner on a single import line. keys
+// remembers the lines of external keys seen so far, a key may only occur once.
+func (u *Usecase) checkImport(ctx context.Context, principal *auth.Principal, banner *entity.Banner, keys map[string]int, line int) error {
+	if banner.ExternalKey != nil {
+		if first, ok := keys[*banner.ExternalKey]; ok {
+			return fmt.Errorf("%w: external_key is already used on line %d", entity.ErrorsNotBody, first)
+		}
+		keys[*banner.ExternalKey] = line
+	}
+
+	if err := checkPublish(principal, banner); err != nil {
+		return err
+	}
+
+	if err := checkSchedule(banner); err != nil {
+		return err
+	}
+
+	flag, err := u.bannerRepo.CheckIfTagsExist(ctx, principal.TenantId, banner.TagsId)
+	if err != nil {
+		return err
+	}
+
+	if !flag {
+		return entity.ErrorsNotFound
+	}
+
+	flag, err = u.bannerRepo.CheckIfFeatureIdExist(ctx, principal.TenantId, banner.FeatureId)
+	if err != nil {
+		return err
+	}
+
+	if !flag {
+		return entity.ErrorsNotFound
+	}
+
+	return u.validateContent(ctx, principal.TenantId, banner)
+}
+
+// isLineError tells errors of a single import line from failures of the import.
+func isLineError(err error) bool {
+	return errors.Is(err, entity.ErrorsNotBody) || errors.Is(err, entity.ErrorsNotFound) ||
+		errors.Is(err, entity.ErrorsConflict) || errors.Is(err, entity.ErrorsForbidden) ||
+		errors.Is(err, entity.ErrorsUnprocessable)
+}
+
+// checkPublish makes sure that only publishers can leave a banner live after a write.
+func checkPublish(principal *auth.Principal, banner *entity.Banner) error {
+	if banner.IsActive != nil && *banner.IsActive && !principal.Can(auth.PermBannerPublish) {
+		return entity.ErrorsForbidden
+	}
+	return nil
+}
+
+// validateContent checks the banner content against the schema of its feature,
+// features without a schema accept any content.
+func (u *Usecase) validateContent(ctx context.Context, tenantId int, banner *entity.Banner) error {
+	rawSchema, err := u.bannerRepo.GetContentSchema(ctx, tenantId, banner.FeatureId)
+	if err != nil || rawSchema == nil {
+		return err
+	}
+
+	contentSchema, err := schema.Compile(rawSchema)
+	if err != nil {
+		return err
+	}
+
+	violations, err := contentSchema.Validate(banner.Content)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) != 0 {
+		validation := &entity.ContentValidationError{}
+		for _, violation := range violations {
+			validation.Violations = append(validation.Violations, entity.ContentViolation(violation))
+		}
+		return validation
+	}
 	return nil
 }
 
-func (r *Usecase) DeleteBanner(bannerId int) error {
-	if err := r.bannerRepo.DeleteBanner(bannerId); err != nil {
-		return fmt.Errorf(deleteBannerMSG, r.bannerRepo.DeleteBanner(bannerId))
+// checkSchedule rejects activation windows that end before they start.
+func checkSchedule(banner *entity.Banner) error {
+	if banner.StartsAt != nil && banner.EndsAt != nil && !banner.StartsAt.Before(*banner.EndsAt) {
+		return fmt.Errorf("%w: starts_at must be before ends_at", entity.ErrorsNotBody)
 	}
 	return nil
 }
@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/banner"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+)
+
+// fakeRepository knows a single feature with its tags, the methods a test
+// doesn't override panic on the nil Repository.
+type fakeRepository struct {
+	banner.Repository
+	featureId int
+	tags      map[int]bool
+	banners   map[int]*entity.Banner
+	versions  map[int]*entity.BannerVersion
+	updated   []entity.Banner
+	job       *entity.DeletionJob
+}
+
+// fakeCache never has a banner, it keeps the feature and tags of the evicted ones.
+type fakeCache struct {
+	banner.Cashe
+	deleted []entity.Banner
+}
+
+func (fakeCache) Get(ctx context.Context, tenantId, tagID int, featureID int) (*entity.UserBanner, error) {
+	return nil, entity.ErrorsNotFound
+}
+
+func (fakeCache) GetById(ctx context.Context, tenantId, bannerId int) (*entity.UserBanner, error) {
+	return nil, entity.ErrorsNotFound
+}
+
+func (fakeCache) Set(ctx context.Context, tenantId, tagID int, featureID int, banner *entity.UserBanner) error {
+	return nil
+}
+
+func (fakeCache) SetById(ctx context.Context, tenantId int, banner *entity.UserBanner) error {
+	return nil
+}
+
+func (f *fakeCache) Delete(ctx context.Context, tenantId int, banner *entity.Banner) error {
+	f.deleted = append(f.deleted, *banner)
+	return nil
+}
+
+// fakeExperiments assigns every user to the same variant and keeps the recorded ones.
+type fakeExperiments struct {
+	assignment *entity.Assignment
+	recorded   []string
+}
+
+func (f *fakeExperiments) Assign(ctx context.Context, tenantId, tagId, featureId int, userId string) (*entity.Assignment, error) {
+	return f.assignment, nil
+}
+
+func (f *fakeExperiments) RecordAssignment(ctx context.Context, tenantId int, assignment *entity.Assignment, userId string) {
+	f.recorded = append(f.recorded, userId)
+}
+
+func (f *fakeRepository) GetBannerById(ctx context.Context, tenantId, bannerId int) (*entity.Banner, error) {
+	if b, ok := f.banners[bannerId]; ok {
+		return b, nil
+	}
+	return nil, entity.ErrorsNotFound
+}
+
+func (f *fakeRepository) GetBanner(ctx context.Context, tenantId, tagId, featureId int, useLastRevision, showInactive bool) (*entity.Banner, error) {
+	for _, b := range f.banners {
+		if b.FeatureId == featureId {
+			for _, id := range b.TagsId {
+				if id == tagId {
+					return b, nil
+				}
+			}
+		}
+	}
+	return nil, entity.ErrorsNotFound
+}
+
+func (f *fakeRepository) GetBannerVersion(ctx context.Context, tenantId, bannerId, version int) (*entity.BannerVersion, error) {
+	if v, ok := f.versions[version]; ok {
+		return v, nil
+	}
+	return nil, entity.ErrorsNotFound
+}
+
+func (f *fakeRepository) CheckIfTagsExist(ctx context.Context, tenantId int, tagIds []int) (bool, error) {
+	for _, id := range tagIds {
+		if !f.tags[id] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (f *fakeRepository) CheckIfFeatureIdExist(ctx context.Context, tenantId, featureId int) (bool, error) {
+	return featureId == f.featureId, nil
+}
+
+func (f *fakeRepository) GetContentSchema(ctx context.Context, tenantId, featureId int) ([]byte, error) {
+	return nil, nil
+}
+
+func (f *fakeRepository) UpdateBanner(ctx context.Context, tenantId int, updBanner *entity.Banner, author string) error {
+	f.updated = append(f.updated, *updBanner)
+	return nil
+}
+
+func (f *fakeRepository) DeleteBanner(ctx context.Context, tenantId, bannerId int) error {
+	delete(f.banners, bannerId)
+	return nil
+}
+
+func (f *fakeRepository) ProcessDeletionBatch(ctx context.Context, batchSize int) (*entity.DeletionJob, error) {
+	return f.job, nil
+}
+
+func TestUsecase_RestoreBannerVersion(t *testing.T) {
+	active := true
+	repo := &fakeRepository{
+		featureId: 1,
+		tags:      map[int]bool{1: true},
+		banners:   map[int]*entity.Banner{5: {BannerId: 5, FeatureId: 1, TagsId: []int{3}}},
+		versions: map[int]*entity.BannerVersion{
+			1: {BannerId: 5, Version: 1, FeatureId: 1, TagsId: []int{1, 2}, Content: map[string]interface{}{}, IsActive: &active},
+			2: {BannerId: 5, Version: 2, FeatureId: 1, TagsId: []int{1}, Content: map[string]interface{}{}, IsActive: &active},
+		},
+	}
+	cache := &fakeCache{}
+	u := NewUsecase(repo, cache, nil)
+	principal := &auth.Principal{Subject: "admin", TenantId: 1, Permissions: map[auth.Permission]struct{}{auth.PermBannerPublish: {}}}
+
+	// tag 2 was deleted after the first revision
+	err := u.RestoreBannerVersion(context.Background(), principal, 5, 1)
+	if !errors.Is(err, entity.ErrorsRevisionGone) {
+		t.Errorf("Expected ErrorsRevisionGone, got %v", err)
+	}
+	if len(repo.updated) != 0 {
+		t.Errorf("Expected the banner to be left as it is, got %v", repo.updated)
+	}
+
+	if err := u.RestoreBannerVersion(context.Background(), principal, 5, 2); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if len(repo.updated) != 1 || repo.updated[0].TagsId[0] != 1 {
+		t.Errorf("Expected the second revision to be restored, got %v", repo.updated)
+	}
+	// the banner was cached under tag 3 before and may be under tag 1 now
+	if len(cache.deleted) != 2 || cache.deleted[0].TagsId[0] != 3 || cache.deleted[1].TagsId[0] != 1 {
+		t.Errorf("Expected the old and the new entries to be evicted, got %v", cache.deleted)
+	}
+}
+
+func TestUsecase_EvictsDeletedBanners(t *testing.T) {
+	repo := &fakeRepository{
+		banners: map[int]*entity.Banner{1: {BannerId: 1, FeatureId: 1, TagsId: []int{1, 2}}},
+		job: &entity.DeletionJob{JobId: 1, TenantId: 2, Removed: []entity.Banner{
+			{BannerId: 2, FeatureId: 1, TagsId: []int{3}},
+			{BannerId: 3, FeatureId: 2, TagsId: []int{3}},
+		}},
+	}
+	cache := &fakeCache{}
+	u := NewUsecase(repo, cache, nil)
+
+	if err := u.DeleteBanner(context.Background(), &auth.Principal{TenantId: 1}, 1); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if len(cache.deleted) != 1 || cache.deleted[0].BannerId != 1 || len(cache.deleted[0].TagsId) != 2 {
+		t.Errorf("Expected banner 1 with its tags to be evicted, got %v", cache.deleted)
+	}
+
+	cache.deleted = nil
+	if _, err := u.ProcessDeletionBatch(context.Background(), 10, entity.RetryPolicy{MaxAttempts: 1}); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if len(cache.deleted) != 2 || cache.deleted[0].BannerId != 2 || cache.deleted[1].BannerId != 3 {
+		t.Errorf("Expected the banners of the batch to be evicted, got %v", cache.deleted)
+	}
+}
+
+func TestFailDeletion(t *testing.T) {
+	retry := entity.RetryPolicy{MaxAttempts: 2, BackoffBase: time.Minute, BackoffMax: time.Hour}
+	now := time.Now()
+
+	job := &entity.DeletionJob{JobId: 1, Status: entity.DeletionStatusRunning}
+	failDeletion(job, retry, errors.New("deadlock detected"), now)
+	if job.Status != entity.DeletionStatusRunning || !job.NextAttempt.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected a retry in a minute, got %s at %v", job.Status, job.NextAttempt)
+	}
+	if job.LastError != "deadlock detected" {
+		t.Errorf("Expected the error to be kept, got %q", job.LastError)
+	}
+
+	failDeletion(job, retry, errors.New("deadlock detected"), now)
+	if job.Status != entity.DeletionStatusDead || job.Attempts != 2 {
+		t.Errorf("Expected dead after 2 attempts, got %s after %d", job.Status, job.Attempts)
+	}
+}
+
+func TestUsecase_GetBannerVariant(t *testing.T) {
+	active := true
+	repo := &fakeRepository{banners: map[int]*entity.Banner{
+		1: {BannerId: 1, FeatureId: 1, TagsId: []int{1}, Content: map[string]interface{}{"title": "regular"}, IsActive: &active},
+		2: {BannerId: 2, FeatureId: 1, TagsId: []int{2}, Content: map[string]interface{}{"title": "variant"}, IsActive: &active},
+	}}
+	experiments := &fakeExperiments{assignment: &entity.Assignment{ExperimentId: 7, Variant: "b", BannerId: 2}}
+	u := NewUsecase(repo, &fakeCache{}, experiments)
+	principal := &auth.Principal{TenantId: 1}
+
+	userBanner, err := u.GetBanner(context.Background(), principal, 1, 1, false, "user-1")
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if userBanner.BannerId != 2 || userBanner.Variant != "b" || len(experiments.recorded) != 1 {
+		t.Errorf("Expected the recorded variant, got banner %d %q with %v", userBanner.BannerId, userBanner.Variant, experiments.recorded)
+	}
+
+	// the variant banner was moved to another feature after the experiment started
+	repo.banners[2].FeatureId = 3
+	userBanner, err = u.GetBanner(context.Background(), principal, 1, 1, false, "user-2")
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if userBanner.BannerId != 1 || userBanner.Variant != "" || len(experiments.recorded) != 1 {
+		t.Errorf("Expected the regular banner without a record, got banner %d %q with %v", userBanner.BannerId, userBanner.Variant, experiments.recorded)
+	}
+
+	// an inactive variant falls back the same way
+	repo.banners[2].FeatureId = 1
+	inactive := false
+	repo.banners[2].IsActive = &inactive
+	userBanner, err = u.GetBanner(context.Background(), principal, 1, 1, false, "user-3")
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if userBanner.BannerId != 1 || len(experiments.recorded) != 1 {
+		t.Errorf("Expected the regular banner without a record, got banner %d with %v", userBanner.BannerId, experiments.recorded)
+	}
+}
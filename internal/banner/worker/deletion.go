@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/banner"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+)
+
+// DeletionWorker drains the bulk deletion queue in the background.
+type DeletionWorker struct {
+	usecase   banner.Usecase
+	log       logger.Logger
+	interval  time.Duration
+	batchSize int
+	retry     entity.RetryPolicy
+	stop      chan struct{}
+	done      chan struct{}
+	// ctx is cancelled when Close runs out of time, aborting the batch in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewDeletionWorker(usecase banner.Usecase, log logger.Logger, interval time.Duration, batchSize int, retry entity.RetryPolicy) *DeletionWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DeletionWorker{
+		usecase:   usecase,
+		log:       log,
+		interval:  interval,
+		batchSize: batchSize,
+		retry:     retry,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+func (w *DeletionWorker) Run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.drain()
+		}
+	}
+}
+
+// drain processes batches until the queue is empty or the worker is stopped.
+func (w *DeletionWorker) drain() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		job, err := w.usecase.ProcessDeletionBatch(w.ctx, w.batchSize, w.retry)
+		if err != nil {
+			if !errors.Is(err, entity.ErrorsNotFound) {
+				w.log.Error(err.Error())
+			}
+			return
+		}
+
+		w.log.Infof("deletion job %d: %s, deleted %d of %d", job.JobId, job.Status, job.Deleted, job.Total)
+	}
+}
+
+func (w *DeletionWorker) Close(ctx context.Context) error {
+	close(w.stop)
+
+	select {
+	case <-w.done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+type APIKey struct {
+	KeyId       int
+	TenantId    int
+	Name        string
+	Scopes      []string
+	ExpiresAt   *time.Time
+	RevokedAt   *time.Time
+	CreatedDate time.Time
+}
@@ -1,13 +1,154 @@
 package entity
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
 
 type Banner struct {
-	BannerId    int
+	BannerId int
+	// Version is the revision of the banner, bumped by every update. An update
+	// with a non-zero Version only applies to that revision.
+	Version int
+	// ExternalKey is set by the client, nil for banners that don't have one
+	ExternalKey *string
 	TagsId      []int
 	FeatureId   int
 	Content     map[string]interface{}
 	IsActive    *bool
+	StartsAt    *time.Time
+	EndsAt      *time.Time
 	CreatedDate time.Time
 	UpdateDate  time.Time
 }
+
+// Live reports whether users get the banner at t: it is active and t falls
+// into its activation window.
+func (b *Banner) Live(t time.Time) bool {
+	if b.IsActive == nil || !*b.IsActive {
+		return false
+	}
+	if b.StartsAt != nil && t.Before(*b.StartsAt) {
+		return false
+	}
+	return b.EndsAt == nil || t.Before(*b.EndsAt)
+}
+
+// UserBanner is the content a user gets for a feature and tag. Variant is only
+// set when an experiment is running on them.
+type UserBanner struct {
+	BannerId  int
+	FeatureId int
+	// Content is already serialized, ETag is a strong validator of it
+	Content json.RawMessage
+	ETag    string
+	// EndsAt is the end of the banner's window, neither the cache nor clients
+	// may keep it longer
+	EndsAt       *time.Time
+	ExperimentId int
+	Variant      string
+}
+
+// NewUserBanner serializes the content of the banner once, for the response
+// and its ETag. Maps are encoded with sorted keys, so equal content always
+// gets the same ETag.
+func NewUserBanner(banner *Banner) (*UserBanner, error) {
+	content, err := json.Marshal(banner.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(content)
+	return &UserBanner{
+		BannerId:  banner.BannerId,
+		FeatureId: banner.FeatureId,
+		Content:   content,
+		ETag:      `"` + hex.EncodeToString(sum[:16]) + `"`,
+		EndsAt:    banner.EndsAt,
+	}, nil
+}
+
+// Orders of banner pages, every one is made unique by banner_id.
+const (
+	SortBannerId  = "banner_id"
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+)
+
+// BannerCursor is a position in the banners of a tenant sorted by Sort. A
+// cursor without a BannerId points before the first banner, otherwise at the
+// banner after which the next page starts; Time is the sort value of that
+// banner unless banners are sorted by id.
+type BannerCursor struct {
+	Sort     string
+	Desc     bool
+	Time     time.Time
+	BannerId int
+}
+
+// After is the cursor to the position right after banner.
+func (c BannerCursor) After(banner *Banner) BannerCursor {
+	next := BannerCursor{Sort: c.Sort, Desc: c.Desc, BannerId: banner.BannerId}
+	switch c.Sort {
+	case SortCreatedAt:
+		next.Time = banner.CreatedDate
+	case SortUpdatedAt:
+		next.Time = banner.UpdateDate
+	}
+	return next
+}
+
+// BannerPage is a page of banners, Next is nil on the last page.
+type BannerPage struct {
+	Banners []Banner
+	Next    *BannerCursor
+}
+
+type BannerVersion struct {
+	BannerId    int
+	Version     int
+	TagsId      []int
+	FeatureId   int
+	Content     map[string]interface{}
+	IsActive    *bool
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	Author      string
+	CreatedDate time.Time
+}
+
+// ImportLine is a banner read from a line of an import, Err is set instead
+// when the line couldn't be read.
+type ImportLine struct {
+	Line   int
+	Banner Banner
+	Err    error
+}
+
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportFailed  = "failed"
+)
+
+// ImportResult is what happened to a single line of an import. BannerId is
+// only set when the banner exists after the import. Previous is the feature
+// and tags an updated banner had before.
+type ImportResult struct {
+	Line        int
+	ExternalKey *string
+	BannerId    int
+	Action      string
+	Err         error
+	Previous    *Banner
+}
+
+// ImportReport describes the whole import. Nothing is written unless
+// Committed, a dry run or a single failed line roll everything back.
+type ImportReport struct {
+	DryRun    bool
+	Committed bool
+	Results   []ImportResult
+}
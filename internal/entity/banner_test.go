@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewUserBanner(t *testing.T) {
+	first, err := NewUserBanner(&Banner{BannerId: 1, Content: map[string]interface{}{"title": "a", "url": "b"}})
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if string(first.Content) != `{"title":"a","url":"b"}` {
+		t.Errorf("Expected serialized content, got %s", first.Content)
+	}
+	if len(first.ETag) != 34 || first.ETag[0] != '"' || first.ETag[33] != '"' {
+		t.Errorf("Expected a quoted strong ETag, got %s", first.ETag)
+	}
+
+	same, _ := NewUserBanner(&Banner{BannerId: 2, Content: map[string]interface{}{"url": "b", "title": "a"}})
+	if same.ETag != first.ETag {
+		t.Errorf("Expected equal content to share the ETag, got %s and %s", first.ETag, same.ETag)
+	}
+
+	changed, _ := NewUserBanner(&Banner{BannerId: 1, Content: map[string]interface{}{"title": "c", "url": "b"}})
+	if changed.ETag == first.ETag {
+		t.Error("Expected changed content to get another ETag")
+	}
+
+	endsAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	ending, _ := NewUserBanner(&Banner{BannerId: 3, Content: map[string]interface{}{}, EndsAt: &endsAt})
+	if ending.EndsAt == nil || !ending.EndsAt.Equal(endsAt) {
+		t.Errorf("Expected the end of the window to be kept, got %v", ending.EndsAt)
+	}
+}
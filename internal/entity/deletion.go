@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DeletionStatusPending = "pending"
+	DeletionStatusRunning = "running"
+	DeletionStatusDone    = "done"
+	// DeletionStatusDead is a job that failed too many times in a row and is no longer picked
+	DeletionStatusDead = "dead"
+)
+
+// DeletionJob is a bulk deletion. Attempts counts the batches that failed in a
+// row, the job is not picked again before NextAttempt. Removed are the banners
+// deleted by the batch that returned the job, with their feature and tags.
+type DeletionJob struct {
+	JobId       int
+	TenantId    int
+	FeatureId   int
+	TagId       int
+	Status      string
+	Total       int
+	Deleted     int
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+	CreatedDate time.Time
+	UpdateDate  time.Time
+	Removed     []Banner
+}
+
+// DeletionBatchError is a batch of the job that failed, the job is as it was
+// picked, before the batch.
+type DeletionBatchError struct {
+	Job DeletionJob
+	Err error
+}
+
+func (e *DeletionBatchError) Error() string {
+	return fmt.Sprintf("deletion job %d: %v", e.Job.JobId, e.Err)
+}
+
+func (e *DeletionBatchError) Unwrap() error {
+	return e.Err
+}
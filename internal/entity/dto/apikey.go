@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+type APIKeyCreateRequestDTO struct {
+	Name      string     `json:"name" validate:"required"`
+	Scopes    []string   `json:"scopes" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func APIKeyCreateDTOToAPIKey(keyDTO APIKeyCreateRequestDTO) entity.APIKey {
+	return entity.APIKey{
+		Name:      keyDTO.Name,
+		Scopes:    keyDTO.Scopes,
+		ExpiresAt: keyDTO.ExpiresAt,
+	}
+}
+
+// APIKeyIssuedResponseDTO is the only response that carries the plain key
+type APIKeyIssuedResponseDTO struct {
+	KeyId int    `json:"key_id"`
+	Key   string `json:"key"`
+}
+
+type APIKeyResponseDTO struct {
+	KeyId       int        `json:"key_id"`
+	Name        string     `json:"name"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedDate time.Time  `json:"created_at"`
+}
+
+func APIKeyToArrayResponseDTO(keys []entity.APIKey) []APIKeyResponseDTO {
+	keysDTO := make([]APIKeyResponseDTO, 0, len(keys))
+	for _, key := range keys {
+		keysDTO = append(keysDTO, APIKeyResponseDTO{
+			KeyId:       key.KeyId,
+			Name:        key.Name,
+			Scopes:      key.Scopes,
+			ExpiresAt:   key.ExpiresAt,
+			RevokedAt:   key.RevokedAt,
+			CreatedDate: key.CreatedDate,
+		})
+	}
+	return keysDTO
+}
@@ -1,6 +1,9 @@
 package dto
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
@@ -18,10 +21,14 @@ func BannerToContentResponseDTO(banner entity.Banner) BannerContentResponseDTO {
 
 type BannerResponseDTO struct {
 	BannerId    int                    `json:"banner_id"`
+	Version     int                    `json:"version"`
+	ExternalKey *string                `json:"external_key,omitempty"`
 	TagsId      []int                  `json:"tag_ids"`
 	FeatureId   int                    `json:"feature_id"`
 	Content     map[string]interface{} `json:"content"`
 	IsActive    *bool                  `json:"is_active"`
+	StartsAt    *time.Time             `json:"starts_at"`
+	EndsAt      *time.Time             `json:"ends_at"`
 	CreatedDate time.Time              `json:"created_at"`
 	UpdateDate  time.Time              `json:"updated_at"`
 }
@@ -34,35 +41,287 @@ func BannerToArrayResponseDTO(banners []entity.Banner) []BannerResponseDTO {
 	return bannersDTO
 }
 
+// ErrInvalidCursor is a cursor this API didn't issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// bannerCursorDTO is the content of a cursor token. Clients get it base64
+// encoded and must treat it as opaque, so the layout may change.
+type bannerCursorDTO struct {
+	Sort     string     `json:"s"`
+	Desc     bool       `json:"d,omitempty"`
+	Time     *time.Time `json:"t,omitempty"`
+	BannerId int        `json:"id"`
+}
+
+func EncodeBannerCursor(cursor entity.BannerCursor) string {
+	cursorDTO := bannerCursorDTO{Sort: cursor.Sort, Desc: cursor.Desc, BannerId: cursor.BannerId}
+	if !cursor.Time.IsZero() {
+		cursorDTO.Time = &cursor.Time
+	}
+
+	// a struct of plain fields always marshals
+	raw, _ := json.Marshal(cursorDTO)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeBannerCursor(token string) (entity.BannerCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return entity.BannerCursor{}, ErrInvalidCursor
+	}
+
+	var cursorDTO bannerCursorDTO
+	if err := json.Unmarshal(raw, &cursorDTO); err != nil || cursorDTO.BannerId <= 0 {
+		return entity.BannerCursor{}, ErrInvalidCursor
+	}
+
+	cursor := entity.BannerCursor{Sort: cursorDTO.Sort, Desc: cursorDTO.Desc, BannerId: cursorDTO.BannerId}
+	switch cursorDTO.Sort {
+	case entity.SortBannerId:
+	case entity.SortCreatedAt, entity.SortUpdatedAt:
+		if cursorDTO.Time == nil {
+			return entity.BannerCursor{}, ErrInvalidCursor
+		}
+		cursor.Time = *cursorDTO.Time
+	default:
+		return entity.BannerCursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+type BannerPageResponseDTO struct {
+	Banners    []BannerResponseDTO `json:"items"`
+	NextCursor *string             `json:"next_cursor"`
+}
+
+func BannerPageToResponseDTO(page entity.BannerPage) BannerPageResponseDTO {
+	pageDTO := BannerPageResponseDTO{Banners: make([]BannerResponseDTO, 0, len(page.Banners))}
+	for _, banner := range page.Banners {
+		pageDTO.Banners = append(pageDTO.Banners, BannerResponseDTO(banner))
+	}
+
+	if page.Next != nil {
+		next := EncodeBannerCursor(*page.Next)
+		pageDTO.NextCursor = &next
+	}
+	return pageDTO
+}
+
 type BannerCreateRequestDTO struct {
-	TagIds    []int                  `json:"tag_ids" validate:"required"`
-	FeatureId int                    `json:"feature_id" validate:"required"`
-	Content   map[string]interface{} `json:"content" validate:"required"`
-	IsActive  *bool                  `json:"is_active" validate:"required"`
+	ExternalKey *string                `json:"external_key" validate:"omitempty,min=1"`
+	TagIds      []int                  `json:"tag_ids" validate:"required"`
+	FeatureId   int                    `json:"feature_id" validate:"required"`
+	Content     map[string]interface{} `json:"content" validate:"required"`
+	IsActive    *bool                  `json:"is_active" validate:"required"`
+	StartsAt    *time.Time             `json:"starts_at"`
+	EndsAt      *time.Time             `json:"ends_at"`
 }
 
 func BannerCreateDToToBanner(bannerDTO BannerCreateRequestDTO) entity.Banner {
 	return entity.Banner{
-		TagsId:    bannerDTO.TagIds,
-		FeatureId: bannerDTO.FeatureId,
-		Content:   bannerDTO.Content,
-		IsActive:  bannerDTO.IsActive,
+		ExternalKey: bannerDTO.ExternalKey,
+		TagsId:      bannerDTO.TagIds,
+		FeatureId:   bannerDTO.FeatureId,
+		Content:     bannerDTO.Content,
+		IsActive:    bannerDTO.IsActive,
+		StartsAt:    bannerDTO.StartsAt,
+		EndsAt:      bannerDTO.EndsAt,
 	}
 }
 
 type BannerUpdateRequestDTO struct {
+	// Version is the revision the update is based on, If-Match can carry it instead
+	Version   int                    `json:"version"`
 	TagIds    []int                  `json:"tag_ids"`
 	FeatureId int                    `json:"feature_id"`
 	Content   map[string]interface{} `json:"content"`
 	IsActive  *bool                  `json:"is_active"`
+	StartsAt  *time.Time             `json:"starts_at"`
+	EndsAt    *time.Time             `json:"ends_at"`
 }
 
 func BannerUpdateDToToBanner(bannerDTO BannerUpdateRequestDTO, id int) entity.Banner {
 	return entity.Banner{
 		BannerId:  id,
+		Version:   bannerDTO.Version,
 		TagsId:    bannerDTO.TagIds,
 		FeatureId: bannerDTO.FeatureId,
 		Content:   bannerDTO.Content,
 		IsActive:  bannerDTO.IsActive,
+		StartsAt:  bannerDTO.StartsAt,
+		EndsAt:    bannerDTO.EndsAt,
+	}
+}
+
+type BannerVersionResponseDTO struct {
+	Version     int                    `json:"version"`
+	TagsId      []int                  `json:"tag_ids"`
+	FeatureId   int                    `json:"feature_id"`
+	Content     map[string]interface{} `json:"content"`
+	IsActive    *bool                  `json:"is_active"`
+	StartsAt    *time.Time             `json:"starts_at"`
+	EndsAt      *time.Time             `json:"ends_at"`
+	Author      string                 `json:"author"`
+	CreatedDate time.Time              `json:"created_at"`
+}
+
+func BannerVersionsToResponseDTO(versions []entity.BannerVersion) []BannerVersionResponseDTO {
+	versionsDTO := make([]BannerVersionResponseDTO, 0, len(versions))
+	for _, version := range versions {
+		versionsDTO = append(versionsDTO, BannerVersionResponseDTO{
+			Version:     version.Version,
+			TagsId:      version.TagsId,
+			FeatureId:   version.FeatureId,
+			Content:     version.Content,
+			IsActive:    version.IsActive,
+			StartsAt:    version.StartsAt,
+			EndsAt:      version.EndsAt,
+			Author:      version.Author,
+			CreatedDate: version.CreatedDate,
+		})
+	}
+	return versionsDTO
+}
+
+type BannerConflictResponseDTO struct {
+	ErrMsg    string `json:"error"`
+	BannerIds []int  `json:"banner_ids"`
+}
+
+func BannerConflictToResponseDTO(conflict entity.BannerConflictError) BannerConflictResponseDTO {
+	return BannerConflictResponseDTO{
+		ErrMsg:    conflict.Error(),
+		BannerIds: conflict.BannerIds,
+	}
+}
+
+type ContentViolationResponseDTO struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type ContentValidationResponseDTO struct {
+	ErrMsg     string                        `json:"error"`
+	Violations []ContentViolationResponseDTO `json:"details"`
+}
+
+func ContentValidationToResponseDTO(validation entity.ContentValidationError) ContentValidationResponseDTO {
+	violationsDTO := make([]ContentViolationResponseDTO, 0, len(validation.Violations))
+	for _, violation := range validation.Violations {
+		violationsDTO = append(violationsDTO, ContentViolationResponseDTO(violation))
+	}
+	return ContentValidationResponseDTO{
+		ErrMsg:     validation.Error(),
+		Violations: violationsDTO,
+	}
+}
+
+// BannerLineDTO is a line of an NDJSON export, imports take the same lines.
+type BannerLineDTO struct {
+	ExternalKey *string                `json:"external_key,omitempty" validate:"omitempty,min=1"`
+	TagIds      []int                  `json:"tag_ids" validate:"required"`
+	FeatureId   int                    `json:"feature_id" validate:"required"`
+	Content     map[string]interface{} `json:"content" validate:"required"`
+	IsActive    *bool                  `json:"is_active" validate:"required"`
+	StartsAt    *time.Time             `json:"starts_at,omitempty"`
+	EndsAt      *time.Time             `json:"ends_at,omitempty"`
+}
+
+func BannerToLineDTO(banner entity.Banner) BannerLineDTO {
+	return BannerLineDTO{
+		ExternalKey: banner.ExternalKey,
+		TagIds:      banner.TagsId,
+		FeatureId:   banner.FeatureId,
+		Content:     banner.Content,
+		IsActive:    banner.IsActive,
+		StartsAt:    banner.StartsAt,
+		EndsAt:      banner.EndsAt,
+	}
+}
+
+func BannerLineDTOToBanner(bannerDTO BannerLineDTO) entity.Banner {
+	return entity.Banner{
+		ExternalKey: bannerDTO.ExternalKey,
+		TagsId:      bannerDTO.TagIds,
+		FeatureId:   bannerDTO.FeatureId,
+		Content:     bannerDTO.Content,
+		IsActive:    bannerDTO.IsActive,
+		StartsAt:    bannerDTO.StartsAt,
+		EndsAt:      bannerDTO.EndsAt,
+	}
+}
+
+type ImportResultResponseDTO struct {
+	Line        int                           `json:"line"`
+	ExternalKey *string                       `json:"external_key,omitempty"`
+	BannerId    int                           `json:"banner_id,omitempty"`
+	Action      string                        `json:"action"`
+	ErrMsg      string                        `json:"error,omitempty"`
+	Violations  []ContentViolationResponseDTO `json:"details,omitempty"`
+}
+
+type ImportReportResponseDTO struct {
+	DryRun    bool                      `json:"dry_run"`
+	Committed bool                      `json:"committed"`
+	Created   int                       `json:"created"`
+	Updated   int                       `json:"updated"`
+	Failed    int                       `json:"failed"`
+	Lines     []ImportResultResponseDTO `json:"lines"`
+}
+
+func ImportReportToResponseDTO(report entity.ImportReport) ImportReportResponseDTO {
+	reportDTO := ImportReportResponseDTO{
+		DryRun:    report.DryRun,
+		Committed: report.Committed,
+		Lines:     make([]ImportResultResponseDTO, 0, len(report.Results)),
+	}
+
+	for _, result := range report.Results {
+		resultDTO := ImportResultResponseDTO{
+			Line:        result.Line,
+			ExternalKey: result.ExternalKey,
+			BannerId:    result.BannerId,
+			Action:      result.Action,
+		}
+
+		switch result.Action {
+		case entity.ImportCreated:
+			reportDTO.Created++
+		case entity.ImportUpdated:
+			reportDTO.Updated++
+		case entity.ImportFailed:
+			reportDTO.Failed++
+			resultDTO.ErrMsg, resultDTO.Violations = importErrorToResponse(result.Err)
+		}
+
+		reportDTO.Lines = append(reportDTO.Lines, resultDTO)
+	}
+	return reportDTO
+}
+
+// importErrorToResponse turns the error of a line into the message a client can
+// act on, the layer prefixes of wrapped errors are of no use there.
+func importErrorToResponse(err error) (string, []ContentViolationResponseDTO) {
+	var validation *entity.ContentValidationError
+	if errors.As(err, &validation) {
+		validationDTO := ContentValidationToResponseDTO(*validation)
+		return validationDTO.ErrMsg, validationDTO.Violations
+	}
+
+	var conflict *entity.BannerConflictError
+	if errors.As(err, &conflict) {
+		return conflict.Error(), nil
+	}
+
+	switch {
+	case errors.Is(err, entity.ErrorsNotBody):
+		return err.Error(), nil
+	case errors.Is(err, entity.ErrorsNotFound):
+		return "feature or tags not found", nil
+	case errors.Is(err, entity.ErrorsConflict):
+		return "external_key is already used by another banner", nil
+	case errors.Is(err, entity.ErrorsForbidden):
+		return "active banners require the banner:publish permission", nil
 	}
+	return err.Error(), nil
 }
@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+func TestBannerCursor(t *testing.T) {
+	cursor := entity.BannerCursor{
+		Sort:     entity.SortUpdatedAt,
+		Desc:     true,
+		Time:     time.Date(2024, 1, 1, 12, 0, 0, 123456000, time.UTC),
+		BannerId: 42,
+	}
+
+	decoded, err := DecodeBannerCursor(EncodeBannerCursor(cursor))
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if decoded != cursor {
+		t.Errorf("Expected %+v, got %+v", cursor, decoded)
+	}
+
+	for _, token := range []string{"", "not base64!", EncodeBannerCursor(entity.BannerCursor{Sort: "content", BannerId: 1}),
+		EncodeBannerCursor(entity.BannerCursor{Sort: entity.SortCreatedAt, BannerId: 1})} {
+		if _, err := DecodeBannerCursor(token); err != ErrInvalidCursor {
+			t.Errorf("Expected invalid cursor for %q, got %v", token, err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+type DeletionJobCreatedDTO struct {
+	JobId int `json:"job_id"`
+}
+
+type DeletionJobResponseDTO struct {
+	JobId       int       `json:"job_id"`
+	FeatureId   int       `json:"feature_id,omitempty"`
+	TagId       int       `json:"tag_id,omitempty"`
+	Status      string    `json:"status"`
+	Total       int       `json:"total"`
+	Deleted     int       `json:"deleted"`
+	Attempts    int       `json:"attempts,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedDate time.Time `json:"created_at"`
+	UpdateDate  time.Time `json:"updated_at"`
+}
+
+func DeletionJobToResponseDTO(job entity.DeletionJob) DeletionJobResponseDTO {
+	return DeletionJobResponseDTO{
+		JobId:       job.JobId,
+		FeatureId:   job.FeatureId,
+		TagId:       job.TagId,
+		Status:      job.Status,
+		Total:       job.Total,
+		Deleted:     job.Deleted,
+		Attempts:    job.Attempts,
+		LastError:   job.LastError,
+		CreatedDate: job.CreatedDate,
+		UpdateDate:  job.UpdateDate,
+	}
+}
@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+type BannerCTRResponseDTO struct {
+	BannerId    int     `json:"banner_id"`
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	CTR         float64 `json:"ctr"`
+}
+
+type CTRReportResponseDTO struct {
+	From    time.Time              `json:"from"`
+	To      time.Time              `json:"to"`
+	Banners []BannerCTRResponseDTO `json:"banners"`
+}
+
+func CTRToResponseDTO(from, to time.Time, stats []entity.BannerCTR) CTRReportResponseDTO {
+	bannersDTO := make([]BannerCTRResponseDTO, 0, len(stats))
+	for _, stat := range stats {
+		bannersDTO = append(bannersDTO, BannerCTRResponseDTO{
+			BannerId:    stat.BannerId,
+			Impressions: stat.Impressions,
+			Clicks:      stat.Clicks,
+			CTR:         stat.CTR(),
+		})
+	}
+
+	return CTRReportResponseDTO{
+		From:    from,
+		To:      to,
+		Banners: bannersDTO,
+	}
+}
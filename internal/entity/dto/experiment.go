@@ -0,0 +1,94 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+type VariantRequestDTO struct {
+	Name     string `json:"name" validate:"required"`
+	BannerId int    `json:"banner_id" validate:"required"`
+	Weight   int    `json:"weight" validate:"required,min=1"`
+}
+
+type ExperimentCreateRequestDTO struct {
+	Name      string              `json:"name" validate:"required"`
+	FeatureId int                 `json:"feature_id" validate:"required"`
+	TagId     int                 `json:"tag_id" validate:"required"`
+	Variants  []VariantRequestDTO `json:"variants" validate:"required,min=2,unique=Name,dive"`
+}
+
+func ExperimentCreateDTOToExperiment(experimentDTO ExperimentCreateRequestDTO) entity.Experiment {
+	variants := make([]entity.Variant, 0, len(experimentDTO.Variants))
+	for _, variant := range experimentDTO.Variants {
+		variants = append(variants, entity.Variant{
+			Name:     variant.Name,
+			BannerId: variant.BannerId,
+			Weight:   variant.Weight,
+		})
+	}
+
+	return entity.Experiment{
+		Name:      experimentDTO.Name,
+		FeatureId: experimentDTO.FeatureId,
+		TagId:     experimentDTO.TagId,
+		IsActive:  true,
+		Variants:  variants,
+	}
+}
+
+type VariantStatsResponseDTO struct {
+	Served int64 `json:"served"`
+	Users  int64 `json:"users"`
+}
+
+type VariantResponseDTO struct {
+	Name        string                   `json:"name"`
+	BannerId    int                      `json:"banner_id"`
+	Weight      int                      `json:"weight"`
+	Assignments *VariantStatsResponseDTO `json:"assignments,omitempty"`
+}
+
+type ExperimentResponseDTO struct {
+	ExperimentId int                  `json:"experiment_id"`
+	Name         string               `json:"name"`
+	FeatureId    int                  `json:"feature_id"`
+	TagId        int                  `json:"tag_id"`
+	IsActive     bool                 `json:"is_active"`
+	Variants     []VariantResponseDTO `json:"variants"`
+	CreatedDate  time.Time            `json:"created_at"`
+}
+
+func ExperimentToResponseDTO(experiment entity.Experiment) ExperimentResponseDTO {
+	variantsDTO := make([]VariantResponseDTO, 0, len(experiment.Variants))
+	for _, variant := range experiment.Variants {
+		variantDTO := VariantResponseDTO{
+			Name:     variant.Name,
+			BannerId: variant.BannerId,
+			Weight:   variant.Weight,
+		}
+		if variant.Stats != nil {
+			variantDTO.Assignments = &VariantStatsResponseDTO{Served: variant.Stats.Served, Users: variant.Stats.Users}
+		}
+		variantsDTO = append(variantsDTO, variantDTO)
+	}
+
+	return ExperimentResponseDTO{
+		ExperimentId: experiment.ExperimentId,
+		Name:         experiment.Name,
+		FeatureId:    experiment.FeatureId,
+		TagId:        experiment.TagId,
+		IsActive:     experiment.IsActive,
+		Variants:     variantsDTO,
+		CreatedDate:  experiment.CreatedDate,
+	}
+}
+
+func ExperimentToArrayResponseDTO(experiments []entity.Experiment) []ExperimentResponseDTO {
+	experimentsDTO := make([]ExperimentResponseDTO, 0, len(experiments))
+	for _, experiment := range experiments {
+		experimentsDTO = append(experimentsDTO, ExperimentToResponseDTO(experiment))
+	}
+	return experimentsDTO
+}
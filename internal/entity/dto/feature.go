@@ -0,0 +1,27 @@
+package dto
+
+import "github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+
+type FeatureResponseDTO struct {
+	FeatureId int    `json:"feature_id"`
+	Name      string `json:"name"`
+}
+
+func FeatureToArrayResponseDTO(features []entity.Feature) []FeatureResponseDTO {
+	featuresDTO := make([]FeatureResponseDTO, 0, len(features))
+	for _, feature := range features {
+		featuresDTO = append(featuresDTO, FeatureResponseDTO(feature))
+	}
+	return featuresDTO
+}
+
+type FeatureRequestDTO struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func FeatureRequestDTOToFeature(featureDTO FeatureRequestDTO, id int) entity.Feature {
+	return entity.Feature{
+		FeatureId: id,
+		Name:      featureDTO.Name,
+	}
+}
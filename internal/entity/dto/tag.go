@@ -0,0 +1,27 @@
+package dto
+
+import "github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+
+type TagResponseDTO struct {
+	TagId int    `json:"tag_id"`
+	Name  string `json:"name"`
+}
+
+func TagToArrayResponseDTO(tags []entity.Tag) []TagResponseDTO {
+	tagsDTO := make([]TagResponseDTO, 0, len(tags))
+	for _, tag := range tags {
+		tagsDTO = append(tagsDTO, TagResponseDTO(tag))
+	}
+	return tagsDTO
+}
+
+type TagRequestDTO struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func TagRequestDTOToTag(tagDTO TagRequestDTO, id int) entity.Tag {
+	return entity.Tag{
+		TagId: id,
+		Name:  tagDTO.Name,
+	}
+}
@@ -0,0 +1,82 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+type WebhookCreateRequestDTO struct {
+	URL string `json:"url" validate:"required,http_url"`
+}
+
+// WebhookCreatedResponseDTO is the only response that carries the secret
+type WebhookCreatedResponseDTO struct {
+	WebhookId   int       `json:"webhook_id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret"`
+	CreatedDate time.Time `json:"created_at"`
+}
+
+func WebhookToCreatedResponseDTO(webhook entity.Webhook) WebhookCreatedResponseDTO {
+	return WebhookCreatedResponseDTO{
+		WebhookId:   webhook.WebhookId,
+		URL:         webhook.URL,
+		Secret:      webhook.Secret,
+		CreatedDate: webhook.CreatedDate,
+	}
+}
+
+type WebhookResponseDTO struct {
+	WebhookId   int       `json:"webhook_id"`
+	URL         string    `json:"url"`
+	CreatedDate time.Time `json:"created_at"`
+}
+
+func WebhookToArrayResponseDTO(webhooks []entity.Webhook) []WebhookResponseDTO {
+	webhooksDTO := make([]WebhookResponseDTO, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		webhooksDTO = append(webhooksDTO, WebhookResponseDTO{
+			WebhookId:   webhook.WebhookId,
+			URL:         webhook.URL,
+			CreatedDate: webhook.CreatedDate,
+		})
+	}
+	return webhooksDTO
+}
+
+type DeliveryResponseDTO struct {
+	DeliveryId     int64     `json:"delivery_id"`
+	WebhookId      int       `json:"webhook_id"`
+	EventId        int64     `json:"event_id"`
+	EventType      string    `json:"event"`
+	BannerId       int       `json:"banner_id"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttempt    time.Time `json:"next_attempt_at"`
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	CreatedDate    time.Time `json:"created_at"`
+	UpdateDate     time.Time `json:"updated_at"`
+}
+
+func DeliveryToArrayResponseDTO(deliveries []entity.Delivery) []DeliveryResponseDTO {
+	deliveriesDTO := make([]DeliveryResponseDTO, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveriesDTO = append(deliveriesDTO, DeliveryResponseDTO{
+			DeliveryId:     delivery.DeliveryId,
+			WebhookId:      delivery.WebhookId,
+			EventId:        delivery.EventId,
+			EventType:      delivery.EventType,
+			BannerId:       delivery.BannerId,
+			Status:         delivery.Status,
+			Attempts:       delivery.Attempts,
+			NextAttempt:    delivery.NextAttempt,
+			LastStatusCode: delivery.LastStatusCode,
+			LastError:      delivery.LastError,
+			CreatedDate:    delivery.CreatedDate,
+			UpdateDate:     delivery.UpdateDate,
+		})
+	}
+	return deliveriesDTO
+}
@@ -1,6 +1,9 @@
 package entity
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 type ResponseError struct {
 	ErrMsg string `json:"error"`
@@ -14,7 +17,82 @@ const (
 )
 
 var (
-	ErrorsNotBody  = errors.New(MsgErrorBody)
-	ErrorsGetPath  = errors.New("GetValueFromUrl: invalid get path")
-	ErrorsNotFound = errors.New("Not found id's")
+	ErrorsNotBody   = errors.New(MsgErrorBody)
+	ErrorsGetPath   = errors.New("GetValueFromUrl: invalid get path")
+	ErrorsNotFound  = errors.New("Not found id's")
+	ErrorsConflict  = errors.New("Conflict")
+	ErrorsForbidden = errors.New("Forbidden")
+	// ErrorsUnprocessable is a well-formed request the data rules reject
+	ErrorsUnprocessable = errors.New("Unprocessable content")
+	// ErrorsStaleVersion is an update of a banner that changed since the client read it
+	ErrorsStaleVersion = errors.New("banner was changed by another request")
+	// ErrorsRevisionGone is a restore of a revision whose feature or tags were deleted since
+	ErrorsRevisionGone = errors.New("feature or tags of the revision no longer exist")
 )
+
+// BannerConflictError reports banners that already serve some of the requested
+// (feature_id, tag_id) pairs.
+type BannerConflictError struct {
+	BannerIds []int
+}
+
+func (e *BannerConflictError) Error() string {
+	return fmt.Sprintf("feature and tags are already used by banners %v", e.BannerIds)
+}
+
+func (e *BannerConflictError) Is(target error) bool {
+	return target == ErrorsConflict
+}
+
+// VariantBannerError reports experiment variants pointing at banners of another
+// feature than the one of the experiment.
+type VariantBannerError struct {
+	BannerIds []int
+}
+
+func (e *VariantBannerError) Error() string {
+	return fmt.Sprintf("banners %v don't belong to the feature of the experiment", e.BannerIds)
+}
+
+func (e *VariantBannerError) Is(target error) bool {
+	return target == ErrorsUnprocessable
+}
+
+// ContentViolation is one place where banner content breaks the schema of its
+// feature, Path is a JSON pointer into the content.
+type ContentViolation struct {
+	Path    string
+	Message string
+}
+
+// ContentValidationError reports banner content that doesn't match the content
+// schema of its feature.
+type ContentValidationError struct {
+	Violations []ContentViolation
+}
+
+func (e *ContentValidationError) Error() string {
+	return fmt.Sprintf("content doesn't match the feature schema: %d violations", len(e.Violations))
+}
+
+func (e *ContentValidationError) Is(target error) bool {
+	return target == ErrorsUnprocessable
+}
+
+// InvalidSchemaError explains why a feature content schema was rejected.
+type InvalidSchemaError struct {
+	Reason string
+}
+
+func (e *InvalidSchemaError) Error() string {
+	return "invalid content schema: " + e.Reason
+}
+
+// WebhookURLError explains why a webhook endpoint may not be called.
+type WebhookURLError struct {
+	Reason string
+}
+
+func (e *WebhookURLError) Error() string {
+	return "webhook url not allowed: " + e.Reason
+}
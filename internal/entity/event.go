@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+const (
+	EventImpression = "impression"
+	EventClick      = "click"
+)
+
+// Event is an impression or a click of a banner. FeatureId, TagId and UserId
+// are zero when unknown, clicks may come without a tag.
+type Event struct {
+	Kind      string
+	TenantId  int
+	BannerId  int
+	FeatureId int
+	TagId     int
+	UserId    string
+	CreatedAt time.Time
+}
+
+// BannerCTR is the click-through rate of a banner over a time range.
+type BannerCTR struct {
+	BannerId    int
+	Impressions int64
+	Clicks      int64
+}
+
+// CTR is clicks per impression, zero for a banner nobody saw.
+func (c BannerCTR) CTR() float64 {
+	if c.Impressions == 0 {
+		return 0
+	}
+	return float64(c.Clicks) / float64(c.Impressions)
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+type Experiment struct {
+	ExperimentId int
+	Name         string
+	FeatureId    int
+	TagId        int
+	IsActive     bool
+	Variants     []Variant
+	CreatedDate  time.Time
+}
+
+type Variant struct {
+	Name     string
+	BannerId int
+	Weight   int
+	// Stats is only loaded for a single experiment, nil otherwise
+	Stats *VariantStats
+}
+
+// VariantStats counts how many times the variant was served and to how many
+// distinct users, the latter is an estimate.
+type VariantStats struct {
+	Served int64
+	Users  int64
+}
+
+// Assignment is the variant of a running experiment a user is put into.
+type Assignment struct {
+	ExperimentId int
+	Variant      string
+	BannerId     int
+}
@@ -0,0 +1,6 @@
+package entity
+
+type Feature struct {
+	FeatureId int
+	Name      string
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// RetryPolicy spaces the attempts of background work. The n-th failed attempt
+// waits BackoffBase * 2^(n-1), at most BackoffMax, after MaxAttempts the work is dead.
+type RetryPolicy struct {
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// Backoff is the wait after the given number of failed attempts.
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	delay := p.BackoffBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= p.BackoffMax {
+			return p.BackoffMax
+		}
+	}
+	if delay > p.BackoffMax {
+		return p.BackoffMax
+	}
+	return delay
+}
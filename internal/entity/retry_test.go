@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BackoffBase: time.Second, BackoffMax: 10 * time.Second}
+
+	for attempts, want := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 8 * time.Second,
+		5: 10 * time.Second,
+		9: 10 * time.Second,
+	} {
+		if got := policy.Backoff(attempts); got != want {
+			t.Errorf("Expected %v after %d attempts, got %v", want, attempts, got)
+		}
+	}
+}
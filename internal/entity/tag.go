@@ -0,0 +1,6 @@
+package entity
+
+type Tag struct {
+	TagId int
+	Name  string
+}
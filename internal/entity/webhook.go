@@ -0,0 +1,49 @@
+package entity
+
+import "time"
+
+// Types of the banner events written to the outbox.
+const (
+	BannerCreated = "banner.created"
+	BannerUpdated = "banner.updated"
+	BannerDeleted = "banner.deleted"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead is a delivery that ran out of attempts, only a retry
+	// brings it back
+	DeliveryDead = "dead"
+)
+
+// Webhook is an endpoint that gets the banner events of its tenant. Secret
+// signs the requests and is only shown when the webhook is created.
+type Webhook struct {
+	WebhookId   int
+	URL         string
+	Secret      string
+	CreatedDate time.Time
+}
+
+// Delivery is a banner event on its way to a webhook. URL, Secret and Payload
+// are only loaded for deliveries claimed for sending.
+type Delivery struct {
+	DeliveryId     int64
+	TenantId       int
+	WebhookId      int
+	EventId        int64
+	EventType      string
+	BannerId       int
+	Status         string
+	Attempts       int
+	NextAttempt    time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedDate    time.Time
+	UpdateDate     time.Time
+
+	URL     string
+	Secret  string
+	Payload []byte
+}
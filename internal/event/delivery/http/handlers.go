@@ -0,0 +1,129 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity/dto"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/event"
+	util "github.com/DmitriyKomarovCoder/banner-api/internal/utils/http"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+)
+
+const (
+	bannerIdPath = "id"
+	// userIdHeader is read when the user_id query parameter is missing, the
+	// same as for user_banner
+	userIdHeader = "X-User-Id"
+	// defaultCTRRange is used when the report is asked without from
+	defaultCTRRange = 24 * time.Hour
+)
+
+var errCTRRange = errors.New("from must be before to")
+
+type Handler struct {
+	usecase event.Usecase
+	log     logger.Logger
+}
+
+func NewHandler(usecase event.Usecase, log logger.Logger) *Handler {
+	return &Handler{
+		usecase: usecase,
+		log:     log,
+	}
+}
+
+// Click records a click-through of the banner, tag_id and user_id are optional.
+func (h *Handler) Click(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(bannerIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	var tagId int
+	if tagIdS := r.URL.Query().Get("tag_id"); tagIdS != "" {
+		tagId, err = strconv.Atoi(tagIdS)
+		if err != nil {
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+			return
+		}
+	}
+
+	userId := r.URL.Query().Get("user_id")
+	if userId == "" {
+		userId = r.Header.Get(userIdHeader)
+	}
+
+	click := entity.Event{
+		TenantId: util.GetPrincipal(r).TenantId,
+		BannerId: id,
+		TagId:    tagId,
+		UserId:   userId,
+	}
+
+	if err := h.usecase.RecordClick(r.Context(), &click); err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCTR reports impressions, clicks and CTR per banner in [from, to). Both are
+// RFC 3339 timestamps, to defaults to now and from to a day before to.
+func (h *Handler) GetCTR(w http.ResponseWriter, r *http.Request) {
+	to := time.Now()
+	if toS := r.URL.Query().Get("to"); toS != "" {
+		parsed, err := time.Parse(time.RFC3339, toS)
+		if err != nil {
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+			return
+		}
+		to = parsed
+	}
+
+	from := to.Add(-defaultCTRRange)
+	if fromS := r.URL.Query().Get("from"); fromS != "" {
+		parsed, err := time.Parse(time.RFC3339, fromS)
+		if err != nil {
+			util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+			return
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		util.ErrorResponse(w, http.StatusBadRequest, errCTRRange, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+		return
+	}
+
+	stats, err := h.usecase.GetCTR(r.Context(), util.GetPrincipal(r).TenantId, from, to)
+	if err != nil {
+		if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.CTRToResponseDTO(from, to, stats))
+}
@@ -0,0 +1,26 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+type Usecase interface {
+	RecordClick(ctx context.Context, click *entity.Event) error
+	GetCTR(ctx context.Context, tenantId int, from, to time.Time) ([]entity.BannerCTR, error)
+}
+
+type Repository interface {
+	// CreateEvents writes a batch of events of any tenants
+	CreateEvents(ctx context.Context, events []entity.Event) error
+	// CreateClick gives ErrorsNotFound for a banner the tenant doesn't have
+	CreateClick(ctx context.Context, click *entity.Event) error
+	GetCTR(ctx context.Context, tenantId int, from, to time.Time) ([]entity.BannerCTR, error)
+}
+
+// Recorder takes impressions off the request path, it must never block.
+type Recorder interface {
+	RecordImpression(impression entity.Event)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ctxutil"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	createEventsMSG = "CreateEvents repository layer: %w"
+	createClickMSG  = "CreateClick repository layer: %w"
+	getCTRMSG       = "GetCTR repository layer: %w"
+	// =============================
+	eventsTable = "banner_events"
+
+	// the feature of a click is taken from the banner, the tag is only known
+	// when the client sends it
+	createClickSQL = `INSERT INTO banner_events (tenant_id, kind, banner_id, feature_id, tag_id, user_id, created_at)
+					  SELECT tenant_id, $3, banner_id, feature_id, $4, $5, $6
+					  FROM banners
+					  WHERE tenant_id = $1 AND banner_id = $2;`
+
+	getCTRSQL = `SELECT banner_id,
+					 COUNT(*) FILTER (WHERE kind = 'impression') AS impressions,
+					 COUNT(*) FILTER (WHERE kind = 'click') AS clicks
+				 FROM banner_events
+				 WHERE tenant_id = $1 AND created_at >= $2 AND created_at < $3
+				 GROUP BY banner_id
+				 ORDER BY banner_id;`
+)
+
+var eventColumns = []string{"tenant_id", "kind", "banner_id", "feature_id", "tag_id", "user_id", "created_at"}
+
+type repository struct {
+	db           *pgxpool.Pool
+	queryTimeout time.Duration
+}
+
+// NewRepository creates postgres repository of impressions and clicks.
+func NewRepository(db *pgxpool.Pool, queryTimeout time.Duration) *repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// CreateEvents copies the batch in a single round trip.
+func (r *repository) CreateEvents(ctx context.Context, events []entity.Event) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows := make([][]interface{}, 0, len(events))
+	for _, event := range events {
+		rows = append(rows, []interface{}{event.TenantId, event.Kind, event.BannerId, nullInt(event.FeatureId),
+			nullInt(event.TagId), nullString(event.UserId), event.CreatedAt})
+	}
+
+	if _, err := r.db.CopyFrom(ctx, pgx.Identifier{eventsTable}, eventColumns, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf(createEventsMSG, err)
+	}
+	return nil
+}
+
+func (r *repository) CreateClick(ctx context.Context, click *entity.Event) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, createClickSQL, click.TenantId, click.BannerId, entity.EventClick,
+		nullInt(click.TagId), nullString(click.UserId), click.CreatedAt)
+	if err != nil {
+		return fmt.Errorf(createClickMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(createClickMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
+
+func (r *repository) GetCTR(ctx context.Context, tenantId int, from, to time.Time) ([]entity.BannerCTR, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, getCTRSQL, tenantId, from, to)
+	if err != nil {
+		return nil, fmt.Errorf(getCTRMSG, err)
+	}
+	defer rows.Close()
+
+	stats := []entity.BannerCTR{}
+	for rows.Next() {
+		var stat entity.BannerCTR
+		if err := rows.Scan(&stat.BannerId, &stat.Impressions, &stat.Clicks); err != nil {
+			return nil, fmt.Errorf(getCTRMSG, err)
+		}
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(getCTRMSG, err)
+	}
+	return stats, nil
+}
+
+// nullInt stores an unknown id as NULL rather than 0.
+func nullInt(v int) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+func nullString(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/event"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/DmitriyKomarovCoder/banner-api/internal/event/usecase")
+
+type Usecase struct {
+	eventRepo event.Repository
+}
+
+func NewUsecase(er event.Repository) *Usecase {
+	return &Usecase{
+		eventRepo: er,
+	}
+}
+
+const (
+	recordClickMSG = "RecordClick usecase layer: %w"
+	getCTRMSG      = "GetCTR usecase layer: %w"
+)
+
+// RecordClick writes the click right away, unlike impressions clicks are rare
+// and the client wants to know the banner exists.
+func (u *Usecase) RecordClick(ctx context.Context, click *entity.Event) error {
+	ctx, span := tracer.Start(ctx, "Usecase.RecordClick")
+	defer span.End()
+
+	click.Kind = entity.EventClick
+	click.CreatedAt = time.Now()
+	if err := u.eventRepo.CreateClick(ctx, click); err != nil {
+		return fmt.Errorf(recordClickMSG, err)
+	}
+	return nil
+}
+
+// GetCTR counts impressions and clicks per banner in [from, to). Impressions
+// still buffered by the recorder are not counted yet.
+func (u *Usecase) GetCTR(ctx context.Context, tenantId int, from, to time.Time) ([]entity.BannerCTR, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetCTR")
+	defer span.End()
+
+	stats, err := u.eventRepo.GetCTR(ctx, tenantId, from, to)
+	if err != nil {
+		return nil, fmt.Errorf(getCTRMSG, err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/event"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/metrics"
+)
+
+// ImpressionRecorder buffers impressions in memory and writes them to postgres
+// in batches, once batchSize are collected or every interval. When postgres
+// can't keep up the buffer fills and new impressions are dropped, serving
+// banners never waits for them.
+type ImpressionRecorder struct {
+	repo      event.Repository
+	log       logger.Logger
+	metrics   *metrics.Metrics
+	interval  time.Duration
+	batchSize int
+	queue     chan entity.Event
+	stop      chan struct{}
+	done      chan struct{}
+	// ctx is cancelled when Close runs out of time, aborting the batch in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewImpressionRecorder(repo event.Repository, log logger.Logger, m *metrics.Metrics, interval time.Duration,
+	batchSize, bufferSize int) *ImpressionRecorder {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ImpressionRecorder{
+		repo:      repo,
+		log:       log,
+		metrics:   m,
+		interval:  interval,
+		batchSize: batchSize,
+		queue:     make(chan entity.Event, bufferSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// RecordImpression queues the impression, or drops it when the buffer is full.
+func (w *ImpressionRecorder) RecordImpression(impression entity.Event) {
+	impression.Kind = entity.EventImpression
+	if impression.CreatedAt.IsZero() {
+		impression.CreatedAt = time.Now()
+	}
+
+	select {
+	case w.queue <- impression:
+	default:
+		w.metrics.ObserveImpressions(metrics.ImpressionDropped, 1)
+	}
+}
+
+func (w *ImpressionRecorder) Run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	batch := make([]entity.Event, 0, w.batchSize)
+	for {
+		select {
+		case <-w.stop:
+			w.drain(batch)
+			return
+		case impression := <-w.queue:
+			batch = append(batch, impression)
+			if len(batch) >= w.batchSize {
+				batch = w.flush(batch)
+			}
+		case <-ticker.C:
+			batch = w.flush(batch)
+		}
+	}
+}
+
+// drain writes everything still buffered, the http server is already shut
+// down when the recorder is stopped so nothing is added meanwhile.
+func (w *ImpressionRecorder) drain(batch []entity.Event) {
+	for {
+		select {
+		case impression := <-w.queue:
+			batch = append(batch, impression)
+			if len(batch) >= w.batchSize {
+				batch = w.flush(batch)
+			}
+		default:
+			w.flush(batch)
+			return
+		}
+	}
+}
+
+// flush writes the batch and returns it emptied. A failed batch is dropped,
+// retrying it would let the buffer grow while postgres is down.
+func (w *ImpressionRecorder) flush(batch []entity.Event) []entity.Event {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := w.repo.CreateEvents(w.ctx, batch); err != nil {
+		w.log.Errorf("impressions: %d lost: %v", len(batch), err)
+		w.metrics.ObserveImpressions(metrics.ImpressionFailed, len(batch))
+	} else {
+		w.metrics.ObserveImpressions(metrics.ImpressionStored, len(batch))
+	}
+	return batch[:0]
+}
+
+func (w *ImpressionRecorder) Close(ctx context.Context) error {
+	close(w.stop)
+
+	select {
+	case <-w.done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
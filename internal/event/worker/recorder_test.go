@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeRepository struct {
+	mu      sync.Mutex
+	batches [][]entity.Event
+}
+
+func (f *fakeRepository) CreateEvents(ctx context.Context, events []entity.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, append([]entity.Event(nil), events...))
+	return nil
+}
+
+func (f *fakeRepository) CreateClick(ctx context.Context, click *entity.Event) error {
+	return nil
+}
+
+func (f *fakeRepository) GetCTR(ctx context.Context, tenantId int, from, to time.Time) ([]entity.BannerCTR, error) {
+	return nil, nil
+}
+
+func (f *fakeRepository) sizes() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sizes := make([]int, 0, len(f.batches))
+	for _, batch := range f.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func newTestRecorder(repo *fakeRepository, batchSize, bufferSize int) *ImpressionRecorder {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return NewImpressionRecorder(repo, logger.Logger{Logger: l}, metrics.New(), time.Hour, batchSize, bufferSize)
+}
+
+func TestImpressionRecorder_FlushesFullBatches(t *testing.T) {
+	repo := &fakeRepository{}
+	recorder := newTestRecorder(repo, 2, 10)
+	go recorder.Run()
+
+	for i := 1; i <= 5; i++ {
+		recorder.RecordImpression(entity.Event{TenantId: 1, BannerId: i})
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(repo.sizes()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the fifth impression waits for the interval or Close
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	sizes := repo.sizes()
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Errorf("Expected batches of 2, 2 and 1, got %v", sizes)
+	}
+	if kind := repo.batches[0][0].Kind; kind != entity.EventImpression {
+		t.Errorf("Expected kind %q, got %q", entity.EventImpression, kind)
+	}
+}
+
+func TestImpressionRecorder_DropsWhenFull(t *testing.T) {
+	repo := &fakeRepository{}
+	// not running, nothing takes impressions off the buffer
+	recorder := newTestRecorder(repo, 10, 3)
+
+	for i := 1; i <= 5; i++ {
+		recorder.RecordImpression(entity.Event{TenantId: 1, BannerId: i})
+	}
+
+	go recorder.Run()
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	sizes := repo.sizes()
+	if len(sizes) != 1 || sizes[0] != 3 {
+		t.Errorf("Expected a single batch of 3, got %v", sizes)
+	}
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity/dto"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/experiment"
+	util "github.com/DmitriyKomarovCoder/banner-api/internal/utils/http"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	experimentIdPath = "id"
+)
+
+type Handler struct {
+	usecase experiment.Usecase
+	log     logger.Logger
+}
+
+func NewHandler(usecase experiment.Usecase, log logger.Logger) *Handler {
+	return &Handler{
+		usecase: usecase,
+		log:     log,
+	}
+}
+
+func (h *Handler) GetExperiments(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := util.GetLimitOffset(r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+		return
+	}
+
+	experiments, err := h.usecase.GetExperiments(r.Context(), util.GetPrincipal(r).TenantId, limit, offset)
+	if err != nil {
+		if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.ExperimentToArrayResponseDTO(experiments))
+}
+
+// GetExperiment shows the config of the experiment and how many times and to
+// how many users each variant was served.
+func (h *Handler) GetExperiment(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(experimentIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	exp, err := h.usecase.GetExperiment(r.Context(), util.GetPrincipal(r).TenantId, id)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.ExperimentToResponseDTO(*exp))
+}
+
+func (h *Handler) CreateExperiment(w http.ResponseWriter, r *http.Request) {
+	var experimentDTO dto.ExperimentCreateRequestDTO
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&experimentDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(experimentDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	exp := dto.ExperimentCreateDTOToExperiment(experimentDTO)
+	experimentId, err := h.usecase.CreateExperiment(r.Context(), util.GetPrincipal(r).TenantId, &exp)
+	if err != nil {
+		var mismatch *entity.VariantBannerError
+		if errors.As(err, &mismatch) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			util.SuccessResponse(w, http.StatusUnprocessableEntity, entity.ResponseError{ErrMsg: mismatch.Error()})
+			return
+		}
+
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if errors.Is(err, entity.ErrorsConflict) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusConflict)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusCreated, experimentId)
+}
+
+func (h *Handler) StopExperiment(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(experimentIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	err = h.usecase.StopExperiment(r.Context(), util.GetPrincipal(r).TenantId, id)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,31 @@
+package experiment
+
+import (
+	"context"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+type Usecase interface {
+	GetExperiments(ctx context.Context, tenantId, limit, offset int) ([]entity.Experiment, error)
+	GetExperiment(ctx context.Context, tenantId, experimentId int) (*entity.Experiment, error)
+	CreateExperiment(ctx context.Context, tenantId int, createExperiment *entity.Experiment) (int, error)
+	StopExperiment(ctx context.Context, tenantId, experimentId int) error
+	// Assign returns nil when no experiment is running on the feature and tag
+	Assign(ctx context.Context, tenantId, tagId, featureId int, userId string) (*entity.Assignment, error)
+	RecordAssignment(ctx context.Context, tenantId int, assignment *entity.Assignment, userId string)
+}
+
+type Repository interface {
+	GetExperiments(ctx context.Context, tenantId, limit, offset int) ([]entity.Experiment, error)
+	GetExperiment(ctx context.Context, tenantId, experimentId int) (*entity.Experiment, error)
+	GetRunningExperiment(ctx context.Context, tenantId, tagId, featureId int) (*entity.Experiment, error)
+	CreateExperiment(ctx context.Context, tenantId int, createExperiment *entity.Experiment) (int, error)
+	StopExperiment(ctx context.Context, tenantId, experimentId int) error
+}
+
+// Counter keeps the assignment counts in redis, they are too hot for postgres.
+type Counter interface {
+	Record(ctx context.Context, tenantId, experimentId int, variant, userId string) error
+	Stats(ctx context.Context, tenantId, experimentId int, variants []string) (map[string]entity.VariantStats, error)
+}
@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ctxutil"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	getExperimentsMSG   = "GetExperiments repository layer: %w"
+	getExperimentMSG    = "GetExperiment repository layer: %w"
+	getRunningMSG       = "GetRunningExperiment repository layer: %w"
+	createExperimentMSG = "CreateExperiment repository layer: %w"
+	stopExperimentMSG   = "StopExperiment repository layer: %w"
+	// =============================
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	// =============================
+	getExperimentsSQL = `SELECT experiment_id, name, feature_id, tag_id, active, created_at
+						 FROM experiments
+						 WHERE tenant_id = $1
+						 ORDER BY experiment_id
+						 LIMIT $2 OFFSET $3;`
+
+	getExperimentSQL = `SELECT experiment_id, name, feature_id, tag_id, active, created_at
+						FROM experiments
+						WHERE tenant_id = $1 AND experiment_id = $2;`
+
+	getRunningSQL = `SELECT experiment_id, name, feature_id, tag_id, active, created_at
+					 FROM experiments
+					 WHERE tenant_id = $1 AND tag_id = $2 AND feature_id = $3 AND active = true;`
+
+	// variants keep a stable order, the assignment of a user depends on it
+	getVariantsSQL = `SELECT experiment_id, name, banner_id, weight
+					  FROM experiment_variants
+					  WHERE tenant_id = $1 AND experiment_id = ANY($2)
+					  ORDER BY experiment_id, name;`
+
+	createExperimentSQL = `INSERT INTO experiments (tenant_id, name, feature_id, tag_id)
+						   VALUES ($1, $2, $3, $4)
+						   RETURNING experiment_id;`
+
+	// the banners are locked until the experiment is committed, so none of them
+	// can be moved to another feature in between
+	lockVariantBannersSQL = `SELECT banner_id, feature_id
+							 FROM banners
+							 WHERE tenant_id = $1 AND banner_id = ANY($2)
+							 FOR SHARE;`
+
+	createVariantSQL = `INSERT INTO experiment_variants (tenant_id, experiment_id, name, banner_id, weight)
+						VALUES ($1, $2, $3, $4, $5);`
+
+	stopExperimentSQL = `UPDATE experiments SET active = false WHERE tenant_id = $1 AND experiment_id = $2 AND active = true;`
+)
+
+type repository struct {
+	db           *pgxpool.Pool
+	queryTimeout time.Duration
+}
+
+// NewRepository creates postgres repository of experiments and their variants.
+func NewRepository(db *pgxpool.Pool, queryTimeout time.Duration) *repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) GetExperiments(ctx context.Context, tenantId, limit, offset int) ([]entity.Experiment, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, getExperimentsSQL, tenantId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getExperimentsMSG, err)
+	}
+	defer rows.Close()
+
+	experiments := []entity.Experiment{}
+	for rows.Next() {
+		var experiment entity.Experiment
+		if err := rows.Scan(&experiment.ExperimentId, &experiment.Name, &experiment.FeatureId, &experiment.TagId,
+			&experiment.IsActive, &experiment.CreatedDate); err != nil {
+			return nil, fmt.Errorf(getExperimentsMSG, err)
+		}
+		experiments = append(experiments, experiment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(getExperimentsMSG, err)
+	}
+
+	if err := r.loadVariants(ctx, tenantId, experiments); err != nil {
+		return nil, fmt.Errorf(getExperimentsMSG, err)
+	}
+
+	return experiments, nil
+}
+
+func (r *repository) GetExperiment(ctx context.Context, tenantId, experimentId int) (*entity.Experiment, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	experiment, err := r.getOne(ctx, tenantId, getExperimentSQL, experimentId)
+	if err != nil {
+		return nil, fmt.Errorf(getExperimentMSG, err)
+	}
+	return experiment, nil
+}
+
+func (r *repository) GetRunningExperiment(ctx context.Context, tenantId, tagId, featureId int) (*entity.Experiment, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	experiment, err := r.getOne(ctx, tenantId, getRunningSQL, tagId, featureId)
+	if err != nil {
+		return nil, fmt.Errorf(getRunningMSG, err)
+	}
+	return experiment, nil
+}
+
+// CreateExperiment stores the experiment with its variants. A feature and tag
+// already under a running experiment give ErrorsConflict, unknown feature, tag
+// or banners give ErrorsNotFound.
+func (r *repository) CreateExperiment(ctx context.Context, tenantId int, createExperiment *entity.Experiment) (int, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf(createExperimentMSG, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkVariantBanners(ctx, tx, tenantId, createExperiment); err != nil {
+		return 0, fmt.Errorf(createExperimentMSG, err)
+	}
+
+	var experimentId int
+	err = tx.QueryRow(ctx, createExperimentSQL, tenantId, createExperiment.Name, createExperiment.FeatureId,
+		createExperiment.TagId).Scan(&experimentId)
+	if err != nil {
+		return 0, fmt.Errorf(createExperimentMSG, constraintError(err))
+	}
+
+	for _, variant := range createExperiment.Variants {
+		_, err = tx.Exec(ctx, createVariantSQL, tenantId, experimentId, variant.Name, variant.BannerId, variant.Weight)
+		if err != nil {
+			return 0, fmt.Errorf(createExperimentMSG, constraintError(err))
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf(createExperimentMSG, err)
+	}
+
+	return experimentId, nil
+}
+
+// checkVariantBanners makes sure every variant shows a banner of the feature of
+// the experiment. Only the feature is compared, a (feature, tag) pair resolves
+// to a single banner, so the other variants are banners of the feature kept
+// off the tag of the experiment.
+func checkVariantBanners(ctx context.Context, tx pgx.Tx, tenantId int, experiment *entity.Experiment) error {
+	bannerIds := make([]int, 0, len(experiment.Variants))
+	for _, variant := range experiment.Variants {
+		bannerIds = append(bannerIds, variant.BannerId)
+	}
+
+	rows, err := tx.Query(ctx, lockVariantBannersSQL, tenantId, bannerIds)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	features := map[int]int{}
+	for rows.Next() {
+		var bannerId, featureId int
+		if err := rows.Scan(&bannerId, &featureId); err != nil {
+			return err
+		}
+		features[bannerId] = featureId
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	mismatch := &entity.VariantBannerError{}
+	for _, bannerId := range bannerIds {
+		featureId, ok := features[bannerId]
+		if !ok {
+			return entity.ErrorsNotFound
+		}
+		if featureId != experiment.FeatureId {
+			mismatch.BannerIds = append(mismatch.BannerIds, bannerId)
+		}
+	}
+	if len(mismatch.BannerIds) > 0 {
+		return mismatch
+	}
+	return nil
+}
+
+func (r *repository) StopExperiment(ctx context.Context, tenantId, experimentId int) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, stopExperimentSQL, tenantId, experimentId)
+	if err != nil {
+		return fmt.Errorf(stopExperimentMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(stopExperimentMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
+
+// getOne runs a query selecting a single experiment, tenantId goes first.
+func (r *repository) getOne(ctx context.Context, tenantId int, query string, args ...interface{}) (*entity.Experiment, error) {
+	var experiment entity.Experiment
+	args = append([]interface{}{tenantId}, args...)
+	err := r.db.QueryRow(ctx, query, args...).Scan(&experiment.ExperimentId, &experiment.Name, &experiment.FeatureId,
+		&experiment.TagId, &experiment.IsActive, &experiment.CreatedDate)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, entity.ErrorsNotFound
+		}
+		return nil, err
+	}
+
+	experiments := []entity.Experiment{experiment}
+	if err := r.loadVariants(ctx, tenantId, experiments); err != nil {
+		return nil, err
+	}
+	return &experiments[0], nil
+}
+
+func (r *repository) loadVariants(ctx context.Context, tenantId int, experiments []entity.Experiment) error {
+	if len(experiments) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(experiments))
+	byId := make(map[int]*entity.Experiment, len(experiments))
+	for i := range experiments {
+		ids = append(ids, experiments[i].ExperimentId)
+		byId[experiments[i].ExperimentId] = &experiments[i]
+	}
+
+	rows, err := r.db.Query(ctx, getVariantsSQL, tenantId, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var experimentId int
+		var variant entity.Variant
+		if err := rows.Scan(&experimentId, &variant.Name, &variant.BannerId, &variant.Weight); err != nil {
+			return err
+		}
+		byId[experimentId].Variants = append(byId[experimentId].Variants, variant)
+	}
+
+	return rows.Err()
+}
+
+func constraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			return entity.ErrorsConflict
+		case foreignKeyViolation:
+			return entity.ErrorsNotFound
+		}
+	}
+	return err
+}
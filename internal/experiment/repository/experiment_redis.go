@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ctxutil"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	recordMSG = "Record counter layer: %w"
+	statsMSG  = "Stats counter layer: %w"
+)
+
+type counter struct {
+	db      *redis.Client
+	timeout time.Duration
+}
+
+// NewCounter creates redis assignment counters, timeout bounds every call.
+func NewCounter(db *redis.Client, timeout time.Duration) *counter {
+	return &counter{
+		db:      db,
+		timeout: timeout,
+	}
+}
+
+// Record counts a served variant. Distinct users go to a HyperLogLog, so the
+// memory stays fixed no matter how many users take part.
+func (c *counter) Record(ctx context.Context, tenantId, experimentId int, variant, userId string) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	pipe := c.db.Pipeline()
+	pipe.HIncrBy(ctx, servedKey(tenantId, experimentId), variant, 1)
+	pipe.PFAdd(ctx, usersKey(tenantId, experimentId, variant), userId)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf(recordMSG, ctxutil.Err(ctx, err))
+	}
+	return nil
+}
+
+func (c *counter) Stats(ctx context.Context, tenantId, experimentId int, variants []string) (map[string]entity.VariantStats, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	pipe := c.db.Pipeline()
+	served := pipe.HGetAll(ctx, servedKey(tenantId, experimentId))
+	users := make(map[string]*redis.IntCmd, len(variants))
+	for _, variant := range variants {
+		users[variant] = pipe.PFCount(ctx, usersKey(tenantId, experimentId, variant))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf(statsMSG, ctxutil.Err(ctx, err))
+	}
+
+	stats := make(map[string]entity.VariantStats, len(variants))
+	for _, variant := range variants {
+		count, _ := strconv.ParseInt(served.Val()[variant], 10, 64)
+		stats[variant] = entity.VariantStats{Served: count, Users: users[variant].Val()}
+	}
+	return stats, nil
+}
+
+func servedKey(tenantId, experimentId int) string {
+	return fmt.Sprintf("experiment:%d:%d:served", tenantId, experimentId)
+}
+
+func usersKey(tenantId, experimentId int, variant string) string {
+	return fmt.Sprintf("experiment:%d:%d:users:%s", tenantId, experimentId, variant)
+}
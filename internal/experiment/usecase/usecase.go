@@ -0,0 +1,237 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/experiment"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var tracer = otel.Tracer("github.com/DmitriyKomarovCoder/banner-api/internal/experiment/usecase")
+
+// maxRunning bounds the cached answers. Feature and tag ids come from the
+// request, so without it any caller could grow the cache with pairs that have
+// nothing running.
+const maxRunning = 10000
+
+type runningKey struct {
+	tenantId, tagId, featureId int
+}
+
+// cachedExperiment also remembers that nothing is running, experiment is nil then.
+type cachedExperiment struct {
+	experiment *entity.Experiment
+	expiresAt  time.Time
+}
+
+type Usecase struct {
+	experimentRepo experiment.Repository
+	counter        experiment.Counter
+	cacheTTL       time.Duration
+
+	mu         sync.RWMutex
+	running    map[runningKey]cachedExperiment
+	maxRunning int
+}
+
+// NewUsecase creates experiment usecase. Every user_banner request asks for the
+// running experiment, so the answer is kept in memory for cacheTTL and a stopped
+// experiment may still be served by other replicas for that long.
+func NewUsecase(er experiment.Repository, c experiment.Counter, cacheTTL time.Duration) *Usecase {
+	return &Usecase{
+		experimentRepo: er,
+		counter:        c,
+		cacheTTL:       cacheTTL,
+		running:        make(map[runningKey]cachedExperiment),
+		maxRunning:     maxRunning,
+	}
+}
+
+const (
+	getExperimentsMSG   = "GetExperiments usecase layer: %w"
+	getExperimentMSG    = "GetExperiment usecase layer: %w"
+	createExperimentMSG = "CreateExperiment usecase layer: %w"
+	stopExperimentMSG   = "StopExperiment usecase layer: %w"
+	assignMSG           = "Assign usecase layer: %w"
+)
+
+func (u *Usecase) GetExperiments(ctx context.Context, tenantId, limit, offset int) ([]entity.Experiment, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetExperiments")
+	defer span.End()
+
+	experiments, err := u.experimentRepo.GetExperiments(ctx, tenantId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getExperimentsMSG, err)
+	}
+	return experiments, nil
+}
+
+// GetExperiment returns the experiment together with the assignment counts of
+// its variants.
+func (u *Usecase) GetExperiment(ctx context.Context, tenantId, experimentId int) (*entity.Experiment, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetExperiment")
+	defer span.End()
+
+	exp, err := u.experimentRepo.GetExperiment(ctx, tenantId, experimentId)
+	if err != nil {
+		return nil, fmt.Errorf(getExperimentMSG, err)
+	}
+
+	names := make([]string, 0, len(exp.Variants))
+	for _, variant := range exp.Variants {
+		names = append(names, variant.Name)
+	}
+
+	stats, err := u.counter.Stats(ctx, tenantId, experimentId, names)
+	if err != nil {
+		return nil, fmt.Errorf(getExperimentMSG, err)
+	}
+
+	for i := range exp.Variants {
+		variantStats := stats[exp.Variants[i].Name]
+		exp.Variants[i].Stats = &variantStats
+	}
+	return exp, nil
+}
+
+func (u *Usecase) CreateExperiment(ctx context.Context, tenantId int, createExperiment *entity.Experiment) (int, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.CreateExperiment")
+	defer span.End()
+
+	experimentId, err := u.experimentRepo.CreateExperiment(ctx, tenantId, createExperiment)
+	if err != nil {
+		return 0, fmt.Errorf(createExperimentMSG, err)
+	}
+
+	u.forget(runningKey{tenantId, createExperiment.TagId, createExperiment.FeatureId})
+	return experimentId, nil
+}
+
+func (u *Usecase) StopExperiment(ctx context.Context, tenantId, experimentId int) error {
+	ctx, span := tracer.Start(ctx, "Usecase.StopExperiment")
+	defer span.End()
+
+	exp, err := u.experimentRepo.GetExperiment(ctx, tenantId, experimentId)
+	if err != nil {
+		return fmt.Errorf(stopExperimentMSG, err)
+	}
+
+	if err := u.experimentRepo.StopExperiment(ctx, tenantId, experimentId); err != nil {
+		return fmt.Errorf(stopExperimentMSG, err)
+	}
+
+	u.forget(runningKey{tenantId, exp.TagId, exp.FeatureId})
+	return nil
+}
+
+// Assign puts the user into a variant of the experiment running on the feature
+// and tag, nil means there is none. The assignment is only counted once the
+// variant is served, see RecordAssignment.
+func (u *Usecase) Assign(ctx context.Context, tenantId, tagId, featureId int, userId string) (*entity.Assignment, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.Assign")
+	defer span.End()
+
+	exp, err := u.runningExperiment(ctx, runningKey{tenantId, tagId, featureId})
+	if err != nil {
+		return nil, fmt.Errorf(assignMSG, err)
+	}
+
+	if exp == nil || len(exp.Variants) == 0 {
+		return nil, nil
+	}
+
+	variant := pickVariant(exp, userId)
+	span.SetAttributes(attribute.Int("experiment.id", exp.ExperimentId), attribute.String("experiment.variant", variant.Name))
+
+	return &entity.Assignment{
+		ExperimentId: exp.ExperimentId,
+		Variant:      variant.Name,
+		BannerId:     variant.BannerId,
+	}, nil
+}
+
+// RecordAssignment counts the user under the variant they were served. A lost
+// count must not cost the user the banner, so errors only end up in the trace.
+func (u *Usecase) RecordAssignment(ctx context.Context, tenantId int, assignment *entity.Assignment, userId string) {
+	ctx, span := tracer.Start(ctx, "Usecase.RecordAssignment")
+	defer span.End()
+
+	if err := u.counter.Record(ctx, tenantId, assignment.ExperimentId, assignment.Variant, userId); err != nil {
+		span.RecordError(err)
+	}
+}
+
+func (u *Usecase) runningExperiment(ctx context.Context, key runningKey) (*entity.Experiment, error) {
+	now := time.Now()
+
+	u.mu.RLock()
+	cached, ok := u.running[key]
+	u.mu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.experiment, nil
+	}
+
+	exp, err := u.experimentRepo.GetRunningExperiment(ctx, key.tenantId, key.tagId, key.featureId)
+	if err != nil && !errors.Is(err, entity.ErrorsNotFound) {
+		return nil, err
+	}
+
+	if u.cacheTTL > 0 {
+		u.remember(key, cachedExperiment{experiment: exp, expiresAt: now.Add(u.cacheTTL)}, now)
+	}
+	return exp, nil
+}
+
+// remember caches the answer for key. A full cache first drops the expired
+// answers, when that doesn't make room the answer is not cached.
+func (u *Usecase) remember(key runningKey, cached cachedExperiment, now time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if _, ok := u.running[key]; !ok && len(u.running) >= u.maxRunning {
+		for k, c := range u.running {
+			if !now.Before(c.expiresAt) {
+				delete(u.running, k)
+			}
+		}
+		if len(u.running) >= u.maxRunning {
+			return
+		}
+	}
+	u.running[key] = cached
+}
+
+func (u *Usecase) forget(key runningKey) {
+	u.mu.Lock()
+	delete(u.running, key)
+	u.mu.Unlock()
+}
+
+// pickVariant hashes the user together with the experiment, so a user keeps
+// the variant for the whole experiment while different experiments split users
+// independently. Variants own ranges of the hash proportional to their weights.
+func pickVariant(exp *entity.Experiment, userId string) entity.Variant {
+	total := 0
+	for _, variant := range exp.Variants {
+		total += variant.Weight
+	}
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d:%s", exp.ExperimentId, userId)
+	point := int(h.Sum64() % uint64(total))
+
+	for _, variant := range exp.Variants {
+		if point < variant.Weight {
+			return variant
+		}
+		point -= variant.Weight
+	}
+	return exp.Variants[len(exp.Variants)-1]
+}
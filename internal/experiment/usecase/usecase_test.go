@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/experiment"
+)
+
+func TestPickVariant(t *testing.T) {
+	exp := &entity.Experiment{
+		ExperimentId: 7,
+		Variants: []entity.Variant{
+			{Name: "a", BannerId: 1, Weight: 1},
+			{Name: "b", BannerId: 2, Weight: 3},
+		},
+	}
+
+	if first, second := pickVariant(exp, "user-42"), pickVariant(exp, "user-42"); first.Name != second.Name {
+		t.Errorf("Expected the same variant for the same user, got %s and %s", first.Name, second.Name)
+	}
+
+	counts := map[string]int{}
+	const users = 10000
+	for i := 0; i < users; i++ {
+		counts[pickVariant(exp, fmt.Sprint("user-", i)).Name]++
+	}
+
+	// weights 1:3 put about a quarter of the users into a
+	if share := float64(counts["a"]) / users; share < 0.22 || share > 0.28 {
+		t.Errorf("Expected about 25%% of users in a, got %.1f%% (%v)", share*100, counts)
+	}
+}
+
+// fakeRepository has nothing running anywhere, the methods a test doesn't
+// override panic on the nil Repository.
+type fakeRepository struct {
+	experiment.Repository
+	calls int
+}
+
+func (f *fakeRepository) GetRunningExperiment(ctx context.Context, tenantId, tagId, featureId int) (*entity.Experiment, error) {
+	f.calls++
+	return nil, entity.ErrorsNotFound
+}
+
+func TestUsecase_RunningCacheIsBounded(t *testing.T) {
+	repo := &fakeRepository{}
+	u := NewUsecase(repo, nil, time.Minute)
+	u.maxRunning = 3
+
+	for tagId := 1; tagId <= 10; tagId++ {
+		if _, err := u.Assign(context.Background(), 1, tagId, 1, "user-1"); err != nil {
+			t.Fatal("Expected nil error, got", err)
+		}
+	}
+	if len(u.running) != 3 {
+		t.Errorf("Expected the cache to stop at 3 answers, got %d", len(u.running))
+	}
+
+	// cached pairs are still answered from memory
+	calls := repo.calls
+	u.Assign(context.Background(), 1, 1, 1, "user-1")
+	if repo.calls != calls {
+		t.Error("Expected a cached answer for tag 1")
+	}
+
+	// expired answers make room for new ones
+	for key, cached := range u.running {
+		cached.expiresAt = time.Now().Add(-time.Second)
+		u.running[key] = cached
+	}
+	u.Assign(context.Background(), 1, 11, 1, "user-1")
+	if _, ok := u.running[runningKey{1, 11, 1}]; !ok || len(u.running) != 1 {
+		t.Errorf("Expected only tag 11 to be cached, got %v", u.running)
+	}
+}
@@ -0,0 +1,229 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity/dto"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/feature"
+	util "github.com/DmitriyKomarovCoder/banner-api/internal/utils/http"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	featureIdPath = "id"
+)
+
+type Handler struct {
+	usecase feature.Usecase
+	log     logger.Logger
+}
+
+func NewHandler(usecase feature.Usecase, log logger.Logger) *Handler {
+	return &Handler{
+		usecase: usecase,
+		log:     log,
+	}
+}
+
+func (h *Handler) GetFeatures(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := util.GetLimitOffset(r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+		return
+	}
+
+	features, err := h.usecase.GetFeatures(util.GetPrincipal(r).TenantId, limit, offset)
+	if err != nil {
+		h.log.FromContext(r.Context()).Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.FeatureToArrayResponseDTO(features))
+}
+
+func (h *Handler) CreateFeature(w http.ResponseWriter, r *http.Request) {
+	var featureDTO dto.FeatureRequestDTO
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&featureDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(featureDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	feature := dto.FeatureRequestDTOToFeature(featureDTO, 0)
+	featureId, err := h.usecase.CreateFeature(util.GetPrincipal(r).TenantId, &feature)
+	if err != nil {
+		h.log.FromContext(r.Context()).Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	util.SuccessResponse(w, http.StatusCreated, featureId)
+}
+
+func (h *Handler) UpdateFeature(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(featureIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	var featureDTO dto.FeatureRequestDTO
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&featureDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(featureDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	feature := dto.FeatureRequestDTOToFeature(featureDTO, id)
+	err = h.usecase.UpdateFeature(util.GetPrincipal(r).TenantId, &feature)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) DeleteFeature(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(featureIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	err = h.usecase.DeleteFeature(util.GetPrincipal(r).TenantId, id)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if errors.Is(err, entity.ErrorsConflict) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusConflict)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetContentSchema(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(featureIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	contentSchema, err := h.usecase.GetContentSchema(util.GetPrincipal(r).TenantId, id)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if contentSchema == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	util.SuccessResponse(w, http.StatusOK, contentSchema)
+}
+
+// SetContentSchema takes the JSON Schema itself as the request body.
+func (h *Handler) SetContentSchema(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(featureIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) || bytes.Equal(body, []byte("null")) {
+		util.ErrorResponse(w, http.StatusBadRequest, entity.ErrorsNotBody, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	err = h.usecase.SetContentSchema(util.GetPrincipal(r).TenantId, id, body)
+	if err != nil {
+		var invalid *entity.InvalidSchemaError
+		if errors.As(err, &invalid) {
+			util.ErrorResponse(w, http.StatusBadRequest, err, invalid.Error(), h.log.FromContext(r.Context()))
+			return
+		} else if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) DeleteContentSchema(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(featureIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	err = h.usecase.SetContentSchema(util.GetPrincipal(r).TenantId, id, nil)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,25 @@
+package feature
+
+import (
+	"encoding/json"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+type Usecase interface {
+	GetFeatures(tenantId, limit, offset int) ([]entity.Feature, error)
+	CreateFeature(tenantId int, createFeature *entity.Feature) (int, error)
+	UpdateFeature(tenantId int, updFeature *entity.Feature) error
+	DeleteFeature(tenantId, featureId int) error
+	GetContentSchema(tenantId, featureId int) (json.RawMessage, error)
+	SetContentSchema(tenantId, featureId int, contentSchema json.RawMessage) error
+}
+
+type Repository interface {
+	GetFeatures(tenantId, limit, offset int) ([]entity.Feature, error)
+	CreateFeature(tenantId int, createFeature *entity.Feature) (int, error)
+	UpdateFeature(tenantId int, updFeature *entity.Feature) error
+	DeleteFeature(tenantId, featureId int) error
+	GetContentSchema(tenantId, featureId int) (json.RawMessage, error)
+	SetContentSchema(tenantId, featureId int, contentSchema json.RawMessage) error
+}
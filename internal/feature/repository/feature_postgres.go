@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	getFeaturesMSG   = "GetFeatures repository layer: %w"
+	createFeatureMSG = "CreateFeature repository layer: %w"
+	updateFeatureMSG = "UpdateFeature repository layer: %w"
+	rmFeatureMSG     = "DeleteFeature repository layer: %w"
+	getSchemaMSG     = "GetContentSchema repository layer: %w"
+	setSchemaMSG     = "SetContentSchema repository layer: %w"
+	// =============================
+	foreignKeyViolation = "23503"
+	// =============================
+	getFeaturesSQL = `SELECT feature_id, name
+					  FROM features
+					  WHERE tenant_id = $1
+					  ORDER BY feature_id
+					  LIMIT $2 OFFSET $3;`
+
+	createFeatureSQL = `INSERT INTO features (tenant_id, name) VALUES ($1, $2) RETURNING feature_id;`
+	updFeatureSQL    = `UPDATE features SET name = $1 WHERE tenant_id = $2 AND feature_id = $3;`
+	rmFeatureSQL     = `DELETE FROM features WHERE tenant_id = $1 AND feature_id = $2;`
+	getSchemaSQL     = `SELECT content_schema FROM features WHERE tenant_id = $1 AND feature_id = $2;`
+	setSchemaSQL     = `UPDATE features SET content_schema = $1 WHERE tenant_id = $2 AND feature_id = $3;`
+)
+
+type repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *repository {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) GetFeatures(tenantId, limit, offset int) ([]entity.Feature, error) {
+	rows, err := r.db.Query(context.Background(), getFeaturesSQL, tenantId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getFeaturesMSG, err)
+	}
+	defer rows.Close()
+
+	features := []entity.Feature{}
+	for rows.Next() {
+		var feature entity.Feature
+		if err := rows.Scan(&feature.FeatureId, &feature.Name); err != nil {
+			return nil, fmt.Errorf(getFeaturesMSG, err)
+		}
+		features = append(features, feature)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(getFeaturesMSG, err)
+	}
+
+	return features, nil
+}
+
+func (r *repository) CreateFeature(tenantId int, createFeature *entity.Feature) (int, error) {
+	var featureId int
+	err := r.db.QueryRow(context.Background(), createFeatureSQL, tenantId, createFeature.Name).Scan(&featureId)
+	if err != nil {
+		return 0, fmt.Errorf(createFeatureMSG, err)
+	}
+	return featureId, nil
+}
+
+func (r *repository) UpdateFeature(tenantId int, updFeature *entity.Feature) error {
+	cmdTag, err := r.db.Exec(context.Background(), updFeatureSQL, updFeature.Name, tenantId, updFeature.FeatureId)
+	if err != nil {
+		return fmt.Errorf(updateFeatureMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(updateFeatureMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
+
+// DeleteFeature relies on the banners foreign key, so a feature that is still
+// used by a banner can't be removed even by a concurrent request.
+func (r *repository) DeleteFeature(tenantId, featureId int) error {
+	cmdTag, err := r.db.Exec(context.Background(), rmFeatureSQL, tenantId, featureId)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return fmt.Errorf(rmFeatureMSG, entity.ErrorsConflict)
+		}
+		return fmt.Errorf(rmFeatureMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(rmFeatureMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
+
+// GetContentSchema returns nil for features without a schema.
+func (r *repository) GetContentSchema(tenantId, featureId int) (json.RawMessage, error) {
+	var contentSchema []byte
+	err := r.db.QueryRow(context.Background(), getSchemaSQL, tenantId, featureId).Scan(&contentSchema)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf(getSchemaMSG, entity.ErrorsNotFound)
+		}
+		return nil, fmt.Errorf(getSchemaMSG, err)
+	}
+	return contentSchema, nil
+}
+
+// SetContentSchema stores the schema, nil removes it.
+func (r *repository) SetContentSchema(tenantId, featureId int, contentSchema json.RawMessage) error {
+	var value interface{}
+	if contentSchema != nil {
+		value = string(contentSchema)
+	}
+
+	cmdTag, err := r.db.Exec(context.Background(), setSchemaSQL, value, tenantId, featureId)
+	if err != nil {
+		return fmt.Errorf(setSchemaMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(setSchemaMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
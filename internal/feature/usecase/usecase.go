@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/feature"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/schema"
+)
+
+type Usecase struct {
+	featureRepo feature.Repository
+}
+
+func NewUsecase(tr feature.Repository) *Usecase {
+	return &Usecase{
+		featureRepo: tr,
+	}
+}
+
+const (
+	getFeaturesMSG   = "GetFeatures usecase layer: %w"
+	createFeatureMSG = "CreateFeature usecase layer: %w"
+	updateFeatureMSG = "UpdateFeature usecase layer: %w"
+	deleteFeatureMSG = "DeleteFeature usecase layer: %w"
+	getSchemaMSG     = "GetContentSchema usecase layer: %w"
+	setSchemaMSG     = "SetContentSchema usecase layer: %w"
+)
+
+func (u *Usecase) GetFeatures(tenantId, limit, offset int) ([]entity.Feature, error) {
+	features, err := u.featureRepo.GetFeatures(tenantId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getFeaturesMSG, err)
+	}
+	return features, nil
+}
+
+func (u *Usecase) CreateFeature(tenantId int, createFeature *entity.Feature) (int, error) {
+	featureId, err := u.featureRepo.CreateFeature(tenantId, createFeature)
+	if err != nil {
+		return 0, fmt.Errorf(createFeatureMSG, err)
+	}
+	return featureId, nil
+}
+
+func (u *Usecase) UpdateFeature(tenantId int, updFeature *entity.Feature) error {
+	if err := u.featureRepo.UpdateFeature(tenantId, updFeature); err != nil {
+		return fmt.Errorf(updateFeatureMSG, err)
+	}
+	return nil
+}
+
+func (u *Usecase) DeleteFeature(tenantId, featureId int) error {
+	if err := u.featureRepo.DeleteFeature(tenantId, featureId); err != nil {
+		return fmt.Errorf(deleteFeatureMSG, err)
+	}
+	return nil
+}
+
+func (u *Usecase) GetContentSchema(tenantId, featureId int) (json.RawMessage, error) {
+	contentSchema, err := u.featureRepo.GetContentSchema(tenantId, featureId)
+	if err != nil {
+		return nil, fmt.Errorf(getSchemaMSG, err)
+	}
+	return contentSchema, nil
+}
+
+// SetContentSchema replaces the schema new and updated banners of the feature
+// are checked against, nil removes it. Banners already stored are not rechecked.
+func (u *Usecase) SetContentSchema(tenantId, featureId int, contentSchema json.RawMessage) error {
+	if contentSchema != nil {
+		if _, err := schema.Compile(contentSchema); err != nil {
+			return fmt.Errorf(setSchemaMSG, &entity.InvalidSchemaError{Reason: err.Error()})
+		}
+	}
+
+	if err := u.featureRepo.SetContentSchema(tenantId, featureId, contentSchema); err != nil {
+		return fmt.Errorf(setSchemaMSG, err)
+	}
+	return nil
+}
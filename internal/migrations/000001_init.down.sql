@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS deletion_requests;
+DROP TABLE IF EXISTS banner_versions;
+DROP TABLE IF EXISTS banner_feature_tags;
+DROP TABLE IF EXISTS banner_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS banners;
+DROP TABLE IF EXISTS features;
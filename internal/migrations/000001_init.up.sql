@@ -0,0 +1,160 @@
+-- every table carries tenant_id and rows of different tenants can only be linked
+-- through composite (tenant_id, id) foreign keys, so a banner can't point at a
+-- feature or tag of another tenant
+
+-- databases created by the former build/sql/initdb.sql already have features,
+-- banners, tags and banner_tags, but without tenant_id. CREATE TABLE IF NOT EXISTS
+-- leaves them as they are, so their rows are moved to tenant 1 and get the
+-- composite keys here, before the tables below reference them
+DO $$
+BEGIN
+    IF to_regclass('features') IS NULL OR EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'features' AND column_name = 'tenant_id'
+    ) THEN
+        RETURN;
+    END IF;
+
+    ALTER TABLE features ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+    ALTER TABLE features ALTER COLUMN tenant_id DROP DEFAULT;
+    ALTER TABLE features ADD UNIQUE (tenant_id, feature_id);
+
+    ALTER TABLE tags ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+    ALTER TABLE tags ALTER COLUMN tenant_id DROP DEFAULT;
+    ALTER TABLE tags ADD UNIQUE (tenant_id, tag_id);
+
+    ALTER TABLE banners ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+    ALTER TABLE banners ALTER COLUMN tenant_id DROP DEFAULT;
+    ALTER TABLE banners ADD UNIQUE (tenant_id, banner_id);
+    ALTER TABLE banners ADD FOREIGN KEY (tenant_id, feature_id) REFERENCES features(tenant_id, feature_id);
+
+    ALTER TABLE banner_tags ADD COLUMN tenant_id INT NOT NULL DEFAULT 1;
+    ALTER TABLE banner_tags ALTER COLUMN tenant_id DROP DEFAULT;
+    ALTER TABLE banner_tags ADD FOREIGN KEY (tenant_id, banner_id) REFERENCES banners(tenant_id, banner_id);
+    ALTER TABLE banner_tags ADD FOREIGN KEY (tenant_id, tag_id) REFERENCES tags(tenant_id, tag_id);
+END $$;
+
+CREATE TABLE IF NOT EXISTS features (
+    feature_id SERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    name VARCHAR NOT NULL,
+    UNIQUE (tenant_id, feature_id)
+);
+
+CREATE TABLE IF NOT EXISTS banners (
+    banner_id SERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    content JSONB NOT NULL,
+    active BOOLEAN NOT NULL,
+    feature_id INT,
+    created_at timestamp DEFAULT now(),
+    update_at timestamp DEFAULT now(),
+    UNIQUE (tenant_id, banner_id),
+    FOREIGN KEY (tenant_id, feature_id) REFERENCES features(tenant_id, feature_id)
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    tag_id SERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    name VARCHAR NOT NULL,
+    UNIQUE (tenant_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS banner_tags (
+    tenant_id INT NOT NULL,
+    banner_id INT,
+    tag_id INT,
+    PRIMARY KEY (banner_id, tag_id),
+    FOREIGN KEY (tenant_id, banner_id) REFERENCES banners(tenant_id, banner_id),
+    FOREIGN KEY (tenant_id, tag_id) REFERENCES tags(tenant_id, tag_id)
+);
+
+-- one row per (feature, tag) pair a banner is served for, the primary key
+-- guarantees that a pair resolves to a single banner
+CREATE TABLE IF NOT EXISTS banner_feature_tags (
+    tenant_id INT NOT NULL,
+    feature_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    banner_id INT NOT NULL,
+    PRIMARY KEY (tenant_id, feature_id, tag_id),
+    FOREIGN KEY (tenant_id, feature_id) REFERENCES features(tenant_id, feature_id),
+    FOREIGN KEY (tenant_id, tag_id) REFERENCES tags(tenant_id, tag_id),
+    FOREIGN KEY (tenant_id, banner_id) REFERENCES banners(tenant_id, banner_id)
+);
+
+CREATE TABLE IF NOT EXISTS banner_versions (
+    tenant_id INT NOT NULL,
+    banner_id INT,
+    version INT NOT NULL,
+    content JSONB NOT NULL,
+    tag_ids INT[] NOT NULL,
+    feature_id INT NOT NULL,
+    active BOOLEAN NOT NULL,
+    author VARCHAR NOT NULL,
+    created_at timestamp DEFAULT now(),
+    PRIMARY KEY (banner_id, version),
+    FOREIGN KEY (tenant_id, banner_id) REFERENCES banners(tenant_id, banner_id)
+);
+
+CREATE TABLE IF NOT EXISTS deletion_requests (
+    request_id SERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    feature_id INT,
+    tag_id INT,
+    status VARCHAR NOT NULL DEFAULT 'pending',
+    total INT NOT NULL DEFAULT 0,
+    deleted INT NOT NULL DEFAULT 0,
+    created_at timestamp DEFAULT now(),
+    update_at timestamp DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    key_id SERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    name VARCHAR NOT NULL,
+    key_hash VARCHAR NOT NULL UNIQUE,
+    scopes VARCHAR[] NOT NULL,
+    expires_at timestamp,
+    revoked_at timestamp,
+    created_at timestamp DEFAULT now()
+);
+
+-- banners that predate banner_feature_tags and banner_versions, i.e. the ones of
+-- an upgraded database, claim their pairs and get their first revision. On a
+-- fresh database there is nothing to copy. The former schema let several banners
+-- serve the same feature and tag, which of them wins is for the operator to
+-- decide, so the upgrade stops and lists them
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(format('feature %s, tag %s: banners %s', feature_id, tag_id, banner_ids), '; ')
+    INTO duplicates
+    FROM (
+        SELECT b.feature_id, bt.tag_id, array_agg(b.banner_id ORDER BY b.banner_id) AS banner_ids
+        FROM banners b
+        JOIN banner_tags bt ON bt.tenant_id = b.tenant_id AND bt.banner_id = b.banner_id
+        WHERE b.feature_id IS NOT NULL
+        GROUP BY b.tenant_id, b.feature_id, bt.tag_id
+        HAVING COUNT(*) > 1
+    ) pairs;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'several banners serve the same feature and tag, leave one banner per pair and migrate again: %', duplicates;
+    END IF;
+END $$;
+
+INSERT INTO banner_feature_tags (tenant_id, feature_id, tag_id, banner_id)
+SELECT b.tenant_id, b.feature_id, bt.tag_id, b.banner_id
+FROM banners b
+JOIN banner_tags bt ON bt.tenant_id = b.tenant_id AND bt.banner_id = b.banner_id
+WHERE b.feature_id IS NOT NULL;
+
+INSERT INTO banner_versions (tenant_id, banner_id, version, content, tag_ids, feature_id, active, author, created_at)
+SELECT b.tenant_id, b.banner_id, 1, b.content,
+       COALESCE((SELECT array_agg(bt.tag_id ORDER BY bt.tag_id) FROM banner_tags bt
+                 WHERE bt.tenant_id = b.tenant_id AND bt.banner_id = b.banner_id), '{}'),
+       b.feature_id, b.active, 'migration', b.update_at
+FROM banners b
+WHERE b.feature_id IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM banner_versions v WHERE v.tenant_id = b.tenant_id AND v.banner_id = b.banner_id);
@@ -0,0 +1,2 @@
+-- nothing to roll back, see the up migration
+SELECT 1;
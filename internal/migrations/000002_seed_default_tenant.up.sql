@@ -0,0 +1,4 @@
+-- the sample tags and features of tenant 1 moved to build/sql/seed.sql, which
+-- is only loaded for local development. The version is kept, so databases that
+-- applied it still migrate down past it
+SELECT 1;
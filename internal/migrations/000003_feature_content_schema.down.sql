@@ -0,0 +1 @@
+ALTER TABLE features DROP COLUMN IF EXISTS content_schema;
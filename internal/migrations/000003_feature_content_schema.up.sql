@@ -0,0 +1,3 @@
+-- optional JSON Schema the content of every banner of the feature has to match,
+-- NULL accepts any content
+ALTER TABLE features ADD COLUMN IF NOT EXISTS content_schema JSONB;
@@ -0,0 +1,8 @@
+ALTER TABLE banner_versions
+    DROP COLUMN IF EXISTS starts_at,
+    DROP COLUMN IF EXISTS ends_at;
+
+ALTER TABLE banners
+    DROP CONSTRAINT IF EXISTS banners_schedule_check,
+    DROP COLUMN IF EXISTS starts_at,
+    DROP COLUMN IF EXISTS ends_at;
@@ -0,0 +1,12 @@
+-- optional activation window, an active banner is only served to users between
+-- starts_at and ends_at, NULL leaves that side open
+ALTER TABLE banners
+    ADD COLUMN IF NOT EXISTS starts_at timestamptz,
+    ADD COLUMN IF NOT EXISTS ends_at timestamptz;
+
+ALTER TABLE banners
+    ADD CONSTRAINT banners_schedule_check CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at);
+
+ALTER TABLE banner_versions
+    ADD COLUMN IF NOT EXISTS starts_at timestamptz,
+    ADD COLUMN IF NOT EXISTS ends_at timestamptz;
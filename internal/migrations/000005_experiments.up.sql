@@ -0,0 +1,30 @@
+-- an experiment splits the users of a feature and tag between several banners,
+-- at most one experiment per (feature, tag) can be running
+CREATE TABLE IF NOT EXISTS experiments (
+    experiment_id SERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    name VARCHAR NOT NULL,
+    feature_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at timestamp DEFAULT now(),
+    UNIQUE (tenant_id, experiment_id),
+    FOREIGN KEY (tenant_id, feature_id) REFERENCES features(tenant_id, feature_id),
+    FOREIGN KEY (tenant_id, tag_id) REFERENCES tags(tenant_id, tag_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS experiments_running_idx
+    ON experiments (tenant_id, feature_id, tag_id) WHERE active;
+
+-- a variant disappears together with its banner, the remaining ones split the
+-- users between them
+CREATE TABLE IF NOT EXISTS experiment_variants (
+    tenant_id INT NOT NULL,
+    experiment_id INT NOT NULL,
+    name VARCHAR NOT NULL,
+    banner_id INT NOT NULL,
+    weight INT NOT NULL CHECK (weight > 0),
+    PRIMARY KEY (experiment_id, name),
+    FOREIGN KEY (tenant_id, experiment_id) REFERENCES experiments(tenant_id, experiment_id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id, banner_id) REFERENCES banners(tenant_id, banner_id) ON DELETE CASCADE
+);
@@ -0,0 +1,16 @@
+-- impressions and clicks of banners. There are no foreign keys on purpose: the
+-- events are written in batches and outlive the banners they count, a batch
+-- must not fail because one of its banners was deleted meanwhile
+CREATE TABLE IF NOT EXISTS banner_events (
+    event_id BIGSERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    kind VARCHAR NOT NULL CHECK (kind IN ('impression', 'click')),
+    banner_id INT NOT NULL,
+    feature_id INT,
+    tag_id INT,
+    user_id VARCHAR,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS banner_events_tenant_created_idx
+    ON banner_events (tenant_id, created_at);
@@ -0,0 +1,6 @@
+-- external_key identifies a banner across environments, imports update the
+-- banner with the same key instead of creating a new one
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS external_key VARCHAR;
+
+CREATE UNIQUE INDEX IF NOT EXISTS banners_external_key_idx
+    ON banners (tenant_id, external_key) WHERE external_key IS NOT NULL;
@@ -0,0 +1,45 @@
+-- banner changes are written to the outbox in the transaction of the change, the
+-- webhook dispatcher fans every event out to the endpoints of its tenant
+CREATE TABLE IF NOT EXISTS outbox (
+    event_id BIGSERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    type VARCHAR NOT NULL,
+    banner_id INT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    dispatched_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (event_id) WHERE dispatched_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    webhook_id SERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    url VARCHAR NOT NULL,
+    secret VARCHAR NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, webhook_id)
+);
+
+-- a delivery is an event on its way to one endpoint. It stays pending while
+-- attempts are left and ends up delivered or dead
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id BIGSERIAL PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    webhook_id INT NOT NULL,
+    event_id BIGINT NOT NULL REFERENCES outbox(event_id) ON DELETE CASCADE,
+    status VARCHAR NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_status_code INT,
+    last_error VARCHAR,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (tenant_id, webhook_id) REFERENCES webhooks(tenant_id, webhook_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx
+    ON webhook_deliveries (tenant_id, webhook_id, delivery_id);
@@ -0,0 +1,8 @@
+-- version is the revision of the current state of the banner, every update bumps
+-- it, so a client can tell that the banner changed since it was read
+ALTER TABLE banners ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+UPDATE banners b
+SET version = v.version
+FROM (SELECT tenant_id, banner_id, MAX(version) AS version FROM banner_versions GROUP BY tenant_id, banner_id) v
+WHERE b.tenant_id = v.tenant_id AND b.banner_id = v.banner_id;
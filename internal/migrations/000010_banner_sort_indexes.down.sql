@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS banners_created_idx;
+DROP INDEX IF EXISTS banners_updated_idx;
@@ -0,0 +1,4 @@
+-- keyset pages of GET /banner read along these indexes instead of sorting all
+-- banners of the tenant, banner_id breaks ties between equal timestamps
+CREATE INDEX IF NOT EXISTS banners_updated_idx ON banners (tenant_id, update_at, banner_id);
+CREATE INDEX IF NOT EXISTS banners_created_idx ON banners (tenant_id, created_at, banner_id);
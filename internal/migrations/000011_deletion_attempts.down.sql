@@ -0,0 +1,3 @@
+ALTER TABLE deletion_requests DROP COLUMN IF EXISTS last_error;
+ALTER TABLE deletion_requests DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE deletion_requests DROP COLUMN IF EXISTS attempts;
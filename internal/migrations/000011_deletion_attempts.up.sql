@@ -0,0 +1,5 @@
+-- a failed batch counts against the job and delays it, so a job that keeps
+-- failing ends up dead instead of holding up the jobs queued after it
+ALTER TABLE deletion_requests ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE deletion_requests ADD COLUMN IF NOT EXISTS next_attempt_at timestamp NOT NULL DEFAULT now();
+ALTER TABLE deletion_requests ADD COLUMN IF NOT EXISTS last_error VARCHAR;
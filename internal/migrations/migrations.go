@@ -0,0 +1,8 @@
+// Package migrations holds the database schema as ordered up/down files
+// applied by pkg/migrate.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity/dto"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/tag"
+	util "github.com/DmitriyKomarovCoder/banner-api/internal/utils/http"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	tagIdPath = "id"
+)
+
+type Handler struct {
+	usecase tag.Usecase
+	log     logger.Logger
+}
+
+func NewHandler(usecase tag.Usecase, log logger.Logger) *Handler {
+	return &Handler{
+		usecase: usecase,
+		log:     log,
+	}
+}
+
+func (h *Handler) GetTags(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := util.GetLimitOffset(r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+		return
+	}
+
+	tags, err := h.usecase.GetTags(util.GetPrincipal(r).TenantId, limit, offset)
+	if err != nil {
+		h.log.FromContext(r.Context()).Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.TagToArrayResponseDTO(tags))
+}
+
+func (h *Handler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var tagDTO dto.TagRequestDTO
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&tagDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(tagDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	tag := dto.TagRequestDTOToTag(tagDTO, 0)
+	tagId, err := h.usecase.CreateTag(util.GetPrincipal(r).TenantId, &tag)
+	if err != nil {
+		h.log.FromContext(r.Context()).Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	util.SuccessResponse(w, http.StatusCreated, tagId)
+}
+
+func (h *Handler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(tagIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	var tagDTO dto.TagRequestDTO
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&tagDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(tagDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	tag := dto.TagRequestDTOToTag(tagDTO, id)
+	err = h.usecase.UpdateTag(util.GetPrincipal(r).TenantId, &tag)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(tagIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	err = h.usecase.DeleteTag(util.GetPrincipal(r).TenantId, id)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if errors.Is(err, entity.ErrorsConflict) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusConflict)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
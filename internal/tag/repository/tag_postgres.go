@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	getTagsMSG   = "GetTags repository layer: %w"
+	createTagMSG = "CreateTag repository layer: %w"
+	updateTagMSG = "UpdateTag repository layer: %w"
+	rmTagMSG     = "DeleteTag repository layer: %w"
+	// =============================
+	foreignKeyViolation = "23503"
+	// =============================
+	getTagsSQL = `SELECT tag_id, name
+				  FROM tags
+				  WHERE tenant_id = $1
+				  ORDER BY tag_id
+				  LIMIT $2 OFFSET $3;`
+
+	createTagSQL = `INSERT INTO tags (tenant_id, name) VALUES ($1, $2) RETURNING tag_id;`
+	updTagSQL    = `UPDATE tags SET name = $1 WHERE tenant_id = $2 AND tag_id = $3;`
+	rmTagSQL     = `DELETE FROM tags WHERE tenant_id = $1 AND tag_id = $2;`
+)
+
+type repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *repository {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) GetTags(tenantId, limit, offset int) ([]entity.Tag, error) {
+	rows, err := r.db.Query(context.Background(), getTagsSQL, tenantId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getTagsMSG, err)
+	}
+	defer rows.Close()
+
+	tags := []entity.Tag{}
+	for rows.Next() {
+		var tag entity.Tag
+		if err := rows.Scan(&tag.TagId, &tag.Name); err != nil {
+			return nil, fmt.Errorf(getTagsMSG, err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(getTagsMSG, err)
+	}
+
+	return tags, nil
+}
+
+func (r *repository) CreateTag(tenantId int, createTag *entity.Tag) (int, error) {
+	var tagId int
+	err := r.db.QueryRow(context.Background(), createTagSQL, tenantId, createTag.Name).Scan(&tagId)
+	if err != nil {
+		return 0, fmt.Errorf(createTagMSG, err)
+	}
+	return tagId, nil
+}
+
+func (r *repository) UpdateTag(tenantId int, updTag *entity.Tag) error {
+	cmdTag, err := r.db.Exec(context.Background(), updTagSQL, updTag.Name, tenantId, updTag.TagId)
+	if err != nil {
+		return fmt.Errorf(updateTagMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(updateTagMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
+
+// DeleteTag relies on the banner_tags foreign key, so a tag that is still
+// attached to a banner can't be removed even by a concurrent request.
+func (r *repository) DeleteTag(tenantId, tagId int) error {
+	cmdTag, err := r.db.Exec(context.Background(), rmTagSQL, tenantId, tagId)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return fmt.Errorf(rmTagMSG, entity.ErrorsConflict)
+		}
+		return fmt.Errorf(rmTagMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(rmTagMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
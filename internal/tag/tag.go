@@ -0,0 +1,19 @@
+package tag
+
+import (
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+type Usecase interface {
+	GetTags(tenantId, limit, offset int) ([]entity.Tag, error)
+	CreateTag(tenantId int, createTag *entity.Tag) (int, error)
+	UpdateTag(tenantId int, updTag *entity.Tag) error
+	DeleteTag(tenantId, tagId int) error
+}
+
+type Repository interface {
+	GetTags(tenantId, limit, offset int) ([]entity.Tag, error)
+	CreateTag(tenantId int, createTag *entity.Tag) (int, error)
+	UpdateTag(tenantId int, updTag *entity.Tag) error
+	DeleteTag(tenantId, tagId int) error
+}
@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/tag"
+)
+
+type Usecase struct {
+	tagRepo tag.Repository
+}
+
+func NewUsecase(tr tag.Repository) *Usecase {
+	return &Usecase{
+		tagRepo: tr,
+	}
+}
+
+const (
+	getTagsMSG   = "GetTags usecase layer: %w"
+	createTagMSG = "CreateTag usecase layer: %w"
+	updateTagMSG = "UpdateTag usecase layer: %w"
+	deleteTagMSG = "DeleteTag usecase layer: %w"
+)
+
+func (u *Usecase) GetTags(tenantId, limit, offset int) ([]entity.Tag, error) {
+	tags, err := u.tagRepo.GetTags(tenantId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getTagsMSG, err)
+	}
+	return tags, nil
+}
+
+func (u *Usecase) CreateTag(tenantId int, createTag *entity.Tag) (int, error) {
+	tagId, err := u.tagRepo.CreateTag(tenantId, createTag)
+	if err != nil {
+		return 0, fmt.Errorf(createTagMSG, err)
+	}
+	return tagId, nil
+}
+
+func (u *Usecase) UpdateTag(tenantId int, updTag *entity.Tag) error {
+	if err := u.tagRepo.UpdateTag(tenantId, updTag); err != nil {
+		return fmt.Errorf(updateTagMSG, err)
+	}
+	return nil
+}
+
+func (u *Usecase) DeleteTag(tenantId, tagId int) error {
+	if err := u.tagRepo.DeleteTag(tenantId, tagId); err != nil {
+		return fmt.Errorf(deleteTagMSG, err)
+	}
+	return nil
+}
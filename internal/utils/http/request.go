@@ -1,16 +1,19 @@
 package http
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
 	"github.com/gorilla/mux"
 )
 
 const (
-	tokenAdmin = "admin"
-	tokenUser  = "user"
+	defaultLimit = 100
+	// MaxLimit bounds the page size of list endpoints
+	MaxLimit = 1000
 )
 
 func GetValueFromUrl(value string, r *http.Request) (int, error) {
@@ -23,11 +26,42 @@ func GetValueFromUrl(value string, r *http.Request) (int, error) {
 	return id, nil
 }
 
-func GetAuthToken(r *http.Request) bool {
-	token := r.Header.Get("token")
-	if token == tokenAdmin {
-		return true
+// GetLimitOffset reads limit/offset query parameters, falling back to the defaults
+// used across list endpoints. limit has to be between 1 and MaxLimit and offset
+// can't be negative.
+func GetLimitOffset(r *http.Request) (int, int, error) {
+	limit, offset := defaultLimit, 0
+	var err error
+
+	if limitS := r.URL.Query().Get("limit"); limitS != "" {
+		limit, err = strconv.Atoi(limitS)
+		if err != nil {
+			return 0, 0, err
+		}
+		if limit < 1 || limit > MaxLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+	}
+
+	if offsetS := r.URL.Query().Get("offset"); offsetS != "" {
+		offset, err = strconv.Atoi(offsetS)
+		if err != nil {
+			return 0, 0, err
+		}
+		if offset < 0 {
+			return 0, 0, fmt.Errorf("offset can't be negative")
+		}
 	}
 
-	return false
+	return limit, offset, nil
+}
+
+// GetPrincipal returns the caller stored by the auth middleware. Requests that
+// bypassed the middleware get a principal without any permissions.
+func GetPrincipal(r *http.Request) *auth.Principal {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		return &auth.Principal{}
+	}
+	return principal
 }
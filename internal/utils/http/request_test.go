@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetLimitOffset(t *testing.T) {
+	tests := []struct {
+		query   string
+		limit   int
+		offset  int
+		wantErr bool
+	}{
+		{query: "", limit: defaultLimit, offset: 0},
+		{query: "limit=10&offset=20", limit: 10, offset: 20},
+		{query: "limit=1000", limit: MaxLimit, offset: 0},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=-1", wantErr: true},
+		{query: "limit=1001", wantErr: true},
+		{query: "offset=-1", wantErr: true},
+		{query: "limit=ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/v1/tag?"+tt.query, nil)
+		limit, offset, err := GetLimitOffset(r)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got limit %d offset %d", tt.query, limit, offset)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.query, err)
+			continue
+		}
+		if limit != tt.limit || offset != tt.offset {
+			t.Errorf("%q: got limit %d offset %d, want %d %d", tt.query, limit, offset, tt.limit, tt.offset)
+		}
+	}
+}
@@ -1,15 +1,35 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"encoding/json"
 
 	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
-	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/sirupsen/logrus"
 )
 
-func ErrorResponse(w http.ResponseWriter, code int, err error, message string, log logger.Logger) {
+// StatusClientClosedRequest is the nginx convention for requests the client
+// abandoned before the response was written.
+const StatusClientClosedRequest = 499
+
+// ContextErrorStatus maps errors caused by the request context: 499 when the
+// client went away and 504 when an operation ran out of its timeout.
+func ContextErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest, true
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, true
+	}
+	return 0, false
+}
+
+// ErrorResponse writes the error response, log should be the request-scoped
+// entry so the line carries the request id.
+func ErrorResponse(w http.ResponseWriter, code int, err error, message string, log *logrus.Entry) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(code)
 
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity/dto"
+	util "github.com/DmitriyKomarovCoder/banner-api/internal/utils/http"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/webhook"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	webhookIdPath  = "id"
+	deliveryIdPath = "id"
+)
+
+type Handler struct {
+	usecase webhook.Usecase
+	log     logger.Logger
+}
+
+func NewHandler(usecase webhook.Usecase, log logger.Logger) *Handler {
+	return &Handler{
+		usecase: usecase,
+		log:     log,
+	}
+}
+
+func (h *Handler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := util.GetLimitOffset(r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+		return
+	}
+
+	webhooks, err := h.usecase.GetWebhooks(r.Context(), util.GetPrincipal(r).TenantId, limit, offset)
+	if err != nil {
+		if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.WebhookToArrayResponseDTO(webhooks))
+}
+
+// CreateWebhook registers an endpoint for the banner events of the tenant, the
+// response has the secret the requests are signed with.
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhookDTO dto.WebhookCreateRequestDTO
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&webhookDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(webhookDTO); err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorBody, h.log.FromContext(r.Context()))
+		return
+	}
+
+	hook, err := h.usecase.CreateWebhook(r.Context(), util.GetPrincipal(r).TenantId, webhookDTO.URL)
+	if err != nil {
+		var forbidden *entity.WebhookURLError
+		if errors.As(err, &forbidden) {
+			util.ErrorResponse(w, http.StatusBadRequest, err, forbidden.Error(), h.log.FromContext(r.Context()))
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusCreated, dto.WebhookToCreatedResponseDTO(*hook))
+}
+
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(webhookIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	err = h.usecase.DeleteWebhook(r.Context(), util.GetPrincipal(r).TenantId, id)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveries shows the delivery attempts of the webhook, newest first,
+// optionally only those with the given status.
+func (h *Handler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(webhookIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	limit, offset, err := util.GetLimitOffset(r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", entity.DeliveryPending, entity.DeliveryDelivered, entity.DeliveryDead:
+	default:
+		util.ErrorResponse(w, http.StatusBadRequest, fmt.Errorf("unknown status %q", status), entity.MsgErrorQuery, h.log.FromContext(r.Context()))
+		return
+	}
+
+	deliveries, err := h.usecase.GetDeliveries(r.Context(), util.GetPrincipal(r).TenantId, id, status, limit, offset)
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	util.SuccessResponse(w, http.StatusOK, dto.DeliveryToArrayResponseDTO(deliveries))
+}
+
+// RetryDelivery puts a dead delivery back into the queue, other deliveries give 404.
+func (h *Handler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := util.GetValueFromUrl(deliveryIdPath, r)
+	if err != nil {
+		util.ErrorResponse(w, http.StatusBadRequest, err, entity.MsgErrorPath, h.log.FromContext(r.Context()))
+		return
+	}
+
+	err = h.usecase.RetryDelivery(r.Context(), util.GetPrincipal(r).TenantId, int64(id))
+	if err != nil {
+		if errors.Is(err, entity.ErrorsNotFound) {
+			h.log.FromContext(r.Context()).Infof("invalid request: %v:", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if code, ok := util.ContextErrorStatus(err); ok {
+			h.log.FromContext(r.Context()).Infof("request aborted: %v:", err)
+			w.WriteHeader(code)
+			return
+		} else {
+			h.log.FromContext(r.Context()).Error(err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+// Headers of a webhook request. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook secret, receivers should also
+// reject old timestamps to stop replays.
+const (
+	EventHeader     = "X-Banner-Event"
+	DeliveryHeader  = "X-Banner-Delivery"
+	TimestampHeader = "X-Banner-Timestamp"
+	SignatureHeader = "X-Banner-Signature"
+
+	signaturePrefix = "sha256="
+	// maxResponseBody is read from the response to let the connection be reused
+	maxResponseBody = 64 << 10
+)
+
+// blockedPrefixes are ranges outside of the ones netip classifies that still
+// never host a public endpoint: "this network" and the carrier-grade NAT space,
+// where some clouds serve instance metadata.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+type sender struct {
+	client        *http.Client
+	allowInsecure bool
+}
+
+// NewSender creates http webhook sender, timeout bounds a whole request and
+// redirects are never followed. Unless allowInsecure is set, which is meant for
+// local development, endpoints have to be https on public addresses. Addresses
+// are checked when connecting, after DNS resolution, so a name that passed
+// can't be rebound to an internal service.
+func NewSender(timeout time.Duration, allowInsecure bool) *sender {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// with a proxy the proxy would be dialed and checked instead of the endpoint
+	transport.Proxy = nil
+	if !allowInsecure {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialPublic}
+		transport.DialContext = dialer.DialContext
+	}
+
+	return &sender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// the redirect is returned as the response and counts as a failed attempt
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowInsecure: allowInsecure,
+	}
+}
+
+// CheckURL rejects endpoints that are refused without resolving them: plain
+// http and internal hosts given by address or as localhost.
+func (s *sender) CheckURL(rawURL string) error {
+	if s.allowInsecure {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return &entity.WebhookURLError{Reason: err.Error()}
+	}
+	if u.Scheme != "https" {
+		return &entity.WebhookURLError{Reason: "https is required"}
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return &entity.WebhookURLError{Reason: host + " is not a public host"}
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		return &entity.WebhookURLError{Reason: host + " is not a public address"}
+	}
+	return nil
+}
+
+// Send posts the payload of the delivery. Any response is returned with its
+// status, the caller decides which ones count as delivered.
+func (s *sender) Send(ctx context.Context, delivery *entity.Delivery) (int, error) {
+	// webhooks registered before the endpoint rules are checked here
+	if err := s.CheckURL(delivery.URL); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.DeliveryId, 10))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, signaturePrefix+Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody)); err != nil {
+		return resp.StatusCode, fmt.Errorf("read response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign computes the signature receivers compare with SignatureHeader, without
+// the prefix.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// dialPublic refuses connections to internal addresses, address is the
+// resolved ip:port about to be connected.
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return &entity.WebhookURLError{Reason: addrPort.Addr().String() + " is not a public address"}
+	}
+	return nil
+}
+
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsMulticast() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+func TestSender_Send(t *testing.T) {
+	payload := []byte(`{"event":"banner.updated","banner_id":7}`)
+	secret := "whsec_test"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		want := signaturePrefix + Sign(secret, r.Header.Get(TimestampHeader), body)
+		if got := r.Header.Get(SignatureHeader); got != want {
+			t.Errorf("Expected signature %s, got %s", want, got)
+		}
+		if got := r.Header.Get(EventHeader); got != entity.BannerUpdated {
+			t.Errorf("Expected event %s, got %s", entity.BannerUpdated, got)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	delivery := &entity.Delivery{DeliveryId: 1, EventType: entity.BannerUpdated, URL: srv.URL, Secret: secret, Payload: payload}
+	code, err := NewSender(time.Second, true).Send(context.Background(), delivery)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if code != http.StatusAccepted {
+		t.Errorf("Expected status %d, got %d", http.StatusAccepted, code)
+	}
+}
+
+func TestSender_Redirect(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the redirect not to be followed")
+	}))
+	defer target.Close()
+
+	srv := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer srv.Close()
+
+	delivery := &entity.Delivery{DeliveryId: 1, EventType: entity.BannerUpdated, URL: srv.URL, Payload: []byte("{}")}
+	code, err := NewSender(time.Second, true).Send(context.Background(), delivery)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+	if code != http.StatusFound {
+		t.Errorf("Expected status %d, got %d", http.StatusFound, code)
+	}
+}
+
+func TestSender_InternalAddress(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no request to reach a loopback endpoint")
+	}))
+	defer srv.Close()
+
+	// the name passes CheckURL, as if it was rebound to loopback it is dialed
+	// at the server, where the address is refused
+	s := NewSender(time.Second, false)
+	transport := s.client.Transport.(*http.Transport)
+	dial := transport.DialContext
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dial(ctx, network, srv.Listener.Addr().String())
+	}
+
+	delivery := &entity.Delivery{DeliveryId: 1, EventType: entity.BannerUpdated, URL: "https://hooks.example.com/banner", Payload: []byte("{}")}
+	var forbidden *entity.WebhookURLError
+	if _, err := s.Send(context.Background(), delivery); !errors.As(err, &forbidden) {
+		t.Errorf("Expected WebhookURLError, got %v", err)
+	}
+}
+
+func TestSender_CheckURL(t *testing.T) {
+	s := NewSender(time.Second, false)
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://hooks.example.com/banner", true},
+		{"https://93.184.216.34/hook", true},
+		{"http://hooks.example.com/banner", false},
+		{"https://localhost:8081/metrics", false},
+		{"https://127.0.0.1/hook", false},
+		{"https://10.0.0.5/hook", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://100.100.100.200/hook", false},
+		{"https://[::1]/hook", false},
+		{"https://[::ffff:127.0.0.1]/hook", false},
+	}
+
+	for _, tt := range tests {
+		err := s.CheckURL(tt.url)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%s: expected allowed %v, got %v", tt.url, tt.allowed, err)
+		}
+	}
+
+	if err := NewSender(time.Second, true).CheckURL("http://localhost:9000/hook"); err != nil {
+		t.Error("Expected insecure sender to allow local endpoints, got", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	const want = "b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if got := Sign("secret", "1700000000", []byte("{}")); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
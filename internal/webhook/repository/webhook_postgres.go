@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ctxutil"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	getWebhooksMSG     = "GetWebhooks repository layer: %w"
+	checkWebhookMSG    = "CheckIfWebhookExist repository layer: %w"
+	createWebhookMSG   = "CreateWebhook repository layer: %w"
+	deleteWebhookMSG   = "DeleteWebhook repository layer: %w"
+	getDeliveriesMSG   = "GetDeliveries repository layer: %w"
+	retryDeliveryMSG   = "RetryDelivery repository layer: %w"
+	fanOutMSG          = "FanOut repository layer: %w"
+	claimDeliveriesMSG = "ClaimDeliveries repository layer: %w"
+	saveAttemptMSG     = "SaveAttempt repository layer: %w"
+	// =============================
+	getWebhooksSQL = `SELECT webhook_id, url, created_at
+					  FROM webhooks
+					  WHERE tenant_id = $1
+					  ORDER BY webhook_id
+					  LIMIT $2 OFFSET $3;`
+
+	checkWebhookSQL = `SELECT COUNT(*) FROM webhooks WHERE tenant_id = $1 AND webhook_id = $2;`
+
+	createWebhookSQL = `INSERT INTO webhooks (tenant_id, url, secret)
+						VALUES ($1, $2, $3)
+						RETURNING webhook_id, created_at;`
+
+	// pending deliveries go away with the webhook
+	deleteWebhookSQL = `DELETE FROM webhooks WHERE tenant_id = $1 AND webhook_id = $2;`
+
+	getDeliveriesSQL = `SELECT d.delivery_id, d.webhook_id, d.event_id, o.type, o.banner_id, d.status, d.attempts,
+							d.next_attempt_at, COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), d.created_at, d.updated_at
+						FROM webhook_deliveries d
+						JOIN outbox o ON o.event_id = d.event_id
+						WHERE d.tenant_id = $1 AND d.webhook_id = $2 AND ($3::varchar = '' OR d.status = $3)
+						ORDER BY d.delivery_id DESC
+						LIMIT $4 OFFSET $5;`
+
+	retryDeliverySQL = `UPDATE webhook_deliveries
+						SET status = 'pending', attempts = 0, next_attempt_at = now(), updated_at = now()
+						WHERE tenant_id = $1 AND delivery_id = $2 AND status = 'dead';`
+
+	// one statement, so an event is either fanned out to every webhook of its
+	// tenant or left in the outbox. Events of tenants without webhooks are
+	// just marked as dispatched
+	fanOutSQL = `WITH events AS (
+					 SELECT event_id, tenant_id
+					 FROM outbox
+					 WHERE dispatched_at IS NULL
+					 ORDER BY event_id
+					 LIMIT $1
+					 FOR UPDATE SKIP LOCKED
+				 ), deliveries AS (
+					 INSERT INTO webhook_deliveries (tenant_id, webhook_id, event_id)
+					 SELECT e.tenant_id, w.webhook_id, e.event_id
+					 FROM events e
+					 JOIN webhooks w ON w.tenant_id = e.tenant_id
+				 )
+				 UPDATE outbox o SET dispatched_at = now()
+				 FROM events e
+				 WHERE o.event_id = e.event_id;`
+
+	claimDeliveriesSQL = `WITH due AS (
+							  SELECT delivery_id
+							  FROM webhook_deliveries
+							  WHERE status = 'pending' AND next_attempt_at <= now()
+							  ORDER BY next_attempt_at
+							  LIMIT $1
+							  FOR UPDATE SKIP LOCKED
+						  ), claimed AS (
+							  UPDATE webhook_deliveries d
+							  SET next_attempt_at = now() + make_interval(secs => $2)
+							  FROM due
+							  WHERE d.delivery_id = due.delivery_id
+							  RETURNING d.delivery_id, d.tenant_id, d.webhook_id, d.event_id, d.attempts
+						  )
+						  SELECT c.delivery_id, c.tenant_id, c.webhook_id, c.event_id, o.type, o.banner_id, c.attempts,
+							  w.url, w.secret, o.payload
+						  FROM claimed c
+						  JOIN webhooks w ON w.webhook_id = c.webhook_id
+						  JOIN outbox o ON o.event_id = c.event_id;`
+
+	saveAttemptSQL = `UPDATE webhook_deliveries
+					  SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = NULLIF($5, 0),
+						  last_error = NULLIF($6, ''), updated_at = now()
+					  WHERE delivery_id = $1;`
+)
+
+type repository struct {
+	db           *pgxpool.Pool
+	queryTimeout time.Duration
+}
+
+// NewRepository creates postgres repository of webhooks and their deliveries.
+func NewRepository(db *pgxpool.Pool, queryTimeout time.Duration) *repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) GetWebhooks(ctx context.Context, tenantId, limit, offset int) ([]entity.Webhook, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, getWebhooksSQL, tenantId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getWebhooksMSG, err)
+	}
+	defer rows.Close()
+
+	webhooks := []entity.Webhook{}
+	for rows.Next() {
+		var webhook entity.Webhook
+		if err := rows.Scan(&webhook.WebhookId, &webhook.URL, &webhook.CreatedDate); err != nil {
+			return nil, fmt.Errorf(getWebhooksMSG, err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(getWebhooksMSG, err)
+	}
+	return webhooks, nil
+}
+
+func (r *repository) CheckIfWebhookExist(ctx context.Context, tenantId, webhookId int) (bool, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var count int
+	if err := r.db.QueryRow(ctx, checkWebhookSQL, tenantId, webhookId).Scan(&count); err != nil {
+		return false, fmt.Errorf(checkWebhookMSG, err)
+	}
+	return count != 0, nil
+}
+
+func (r *repository) CreateWebhook(ctx context.Context, tenantId int, webhook *entity.Webhook) (int, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx, createWebhookSQL, tenantId, webhook.URL, webhook.Secret).Scan(&webhook.WebhookId, &webhook.CreatedDate)
+	if err != nil {
+		return 0, fmt.Errorf(createWebhookMSG, err)
+	}
+	return webhook.WebhookId, nil
+}
+
+func (r *repository) DeleteWebhook(ctx context.Context, tenantId, webhookId int) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, deleteWebhookSQL, tenantId, webhookId)
+	if err != nil {
+		return fmt.Errorf(deleteWebhookMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(deleteWebhookMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
+
+func (r *repository) GetDeliveries(ctx context.Context, tenantId, webhookId int, status string, limit, offset int) ([]entity.Delivery, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, getDeliveriesSQL, tenantId, webhookId, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getDeliveriesMSG, err)
+	}
+	defer rows.Close()
+
+	deliveries := []entity.Delivery{}
+	for rows.Next() {
+		delivery := entity.Delivery{TenantId: tenantId}
+		if err := rows.Scan(&delivery.DeliveryId, &delivery.WebhookId, &delivery.EventId, &delivery.EventType,
+			&delivery.BannerId, &delivery.Status, &delivery.Attempts, &delivery.NextAttempt, &delivery.LastStatusCode,
+			&delivery.LastError, &delivery.CreatedDate, &delivery.UpdateDate); err != nil {
+			return nil, fmt.Errorf(getDeliveriesMSG, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(getDeliveriesMSG, err)
+	}
+	return deliveries, nil
+}
+
+func (r *repository) RetryDelivery(ctx context.Context, tenantId int, deliveryId int64) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, retryDeliverySQL, tenantId, deliveryId)
+	if err != nil {
+		return fmt.Errorf(retryDeliveryMSG, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf(retryDeliveryMSG, entity.ErrorsNotFound)
+	}
+	return nil
+}
+
+func (r *repository) FanOut(ctx context.Context, batchSize int) (int, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, fanOutSQL, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf(fanOutMSG, err)
+	}
+	return int(cmdTag.RowsAffected()), nil
+}
+
+func (r *repository) ClaimDeliveries(ctx context.Context, batchSize int, lease time.Duration) ([]entity.Delivery, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, claimDeliveriesSQL, batchSize, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf(claimDeliveriesMSG, err)
+	}
+	defer rows.Close()
+
+	deliveries := []entity.Delivery{}
+	for rows.Next() {
+		delivery := entity.Delivery{Status: entity.DeliveryPending}
+		if err := rows.Scan(&delivery.DeliveryId, &delivery.TenantId, &delivery.WebhookId, &delivery.EventId,
+			&delivery.EventType, &delivery.BannerId, &delivery.Attempts, &delivery.URL, &delivery.Secret,
+			&delivery.Payload); err != nil {
+			return nil, fmt.Errorf(claimDeliveriesMSG, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(claimDeliveriesMSG, err)
+	}
+	return deliveries, nil
+}
+
+func (r *repository) SaveAttempt(ctx context.Context, delivery *entity.Delivery) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.db.Exec(ctx, saveAttemptSQL, delivery.DeliveryId, delivery.Status, delivery.Attempts,
+		delivery.NextAttempt, delivery.LastStatusCode, delivery.LastError)
+	if err != nil {
+		return fmt.Errorf(saveAttemptMSG, err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+	"github.com/DmitriyKomarovCoder/banner-api/internal/webhook"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var tracer = otel.Tracer("github.com/DmitriyKomarovCoder/banner-api/internal/webhook/usecase")
+
+const (
+	secretPrefix = "whsec_"
+	secretBytes  = 32
+)
+
+type Usecase struct {
+	webhookRepo webhook.Repository
+	sender      webhook.Sender
+	retry       entity.RetryPolicy
+	lease       time.Duration
+}
+
+// NewUsecase creates webhook usecase. lease must outlast a send, a delivery
+// still in flight when it runs out is sent once more by another replica.
+func NewUsecase(wr webhook.Repository, s webhook.Sender, retry entity.RetryPolicy, lease time.Duration) *Usecase {
+	return &Usecase{
+		webhookRepo: wr,
+		sender:      s,
+		retry:       retry,
+		lease:       lease,
+	}
+}
+
+const (
+	getWebhooksMSG       = "GetWebhooks usecase layer: %w"
+	createWebhookMSG     = "CreateWebhook usecase layer: %w"
+	deleteWebhookMSG     = "DeleteWebhook usecase layer: %w"
+	getDeliveriesMSG     = "GetDeliveries usecase layer: %w"
+	retryDeliveryMSG     = "RetryDelivery usecase layer: %w"
+	processDeliveriesMSG = "ProcessDeliveries usecase layer: %w"
+)
+
+func (u *Usecase) GetWebhooks(ctx context.Context, tenantId, limit, offset int) ([]entity.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetWebhooks")
+	defer span.End()
+
+	webhooks, err := u.webhookRepo.GetWebhooks(ctx, tenantId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getWebhooksMSG, err)
+	}
+	return webhooks, nil
+}
+
+// CreateWebhook registers the url with a new secret. Unlike api keys the secret
+// is stored as is, every request is signed with it.
+func (u *Usecase) CreateWebhook(ctx context.Context, tenantId int, url string) (*entity.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.CreateWebhook")
+	defer span.End()
+
+	if err := u.sender.CheckURL(url); err != nil {
+		return nil, fmt.Errorf(createWebhookMSG, err)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, fmt.Errorf(createWebhookMSG, err)
+	}
+
+	hook := &entity.Webhook{URL: url, Secret: secret}
+	if _, err := u.webhookRepo.CreateWebhook(ctx, tenantId, hook); err != nil {
+		return nil, fmt.Errorf(createWebhookMSG, err)
+	}
+	return hook, nil
+}
+
+func (u *Usecase) DeleteWebhook(ctx context.Context, tenantId, webhookId int) error {
+	ctx, span := tracer.Start(ctx, "Usecase.DeleteWebhook")
+	defer span.End()
+
+	if err := u.webhookRepo.DeleteWebhook(ctx, tenantId, webhookId); err != nil {
+		return fmt.Errorf(deleteWebhookMSG, err)
+	}
+	return nil
+}
+
+// GetDeliveries lists the deliveries of the webhook, newest first. An empty
+// status lists all of them.
+func (u *Usecase) GetDeliveries(ctx context.Context, tenantId, webhookId int, status string, limit, offset int) ([]entity.Delivery, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetDeliveries")
+	defer span.End()
+
+	flag, err := u.webhookRepo.CheckIfWebhookExist(ctx, tenantId, webhookId)
+	if err != nil {
+		return nil, fmt.Errorf(getDeliveriesMSG, err)
+	}
+
+	if !flag {
+		return nil, fmt.Errorf(getDeliveriesMSG, entity.ErrorsNotFound)
+	}
+
+	deliveries, err := u.webhookRepo.GetDeliveries(ctx, tenantId, webhookId, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf(getDeliveriesMSG, err)
+	}
+	return deliveries, nil
+}
+
+// RetryDelivery gives a dead delivery a fresh set of attempts.
+func (u *Usecase) RetryDelivery(ctx context.Context, tenantId int, deliveryId int64) error {
+	ctx, span := tracer.Start(ctx, "Usecase.RetryDelivery")
+	defer span.End()
+
+	if err := u.webhookRepo.RetryDelivery(ctx, tenantId, deliveryId); err != nil {
+		return fmt.Errorf(retryDeliveryMSG, err)
+	}
+	return nil
+}
+
+// ProcessDeliveries moves new outbox events to the delivery queue and sends up
+// to batchSize due deliveries at once. Delivery is at least once: a replica that
+// dies between sending and saving the attempt sends again after the lease.
+func (u *Usecase) ProcessDeliveries(ctx context.Context, batchSize int) (int, error) {
+	ctx, span := tracer.Start(ctx, "Usecase.ProcessDeliveries")
+	defer span.End()
+
+	if _, err := u.webhookRepo.FanOut(ctx, batchSize); err != nil {
+		return 0, fmt.Errorf(processDeliveriesMSG, err)
+	}
+
+	deliveries, err := u.webhookRepo.ClaimDeliveries(ctx, batchSize, u.lease)
+	if err != nil {
+		return 0, fmt.Errorf(processDeliveriesMSG, err)
+	}
+	span.SetAttributes(attribute.Int("webhook.deliveries", len(deliveries)))
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *entity.Delivery) {
+			defer wg.Done()
+			if err := u.attempt(ctx, delivery); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return len(deliveries), fmt.Errorf(processDeliveriesMSG, err)
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends the delivery once and saves the outcome, any 2xx counts as delivered.
+func (u *Usecase) attempt(ctx context.Context, delivery *entity.Delivery) error {
+	code, err := u.sender.Send(ctx, delivery)
+	u.record(delivery, code, err, time.Now())
+	return u.webhookRepo.SaveAttempt(ctx, delivery)
+}
+
+// record applies the result of an attempt to the delivery.
+func (u *Usecase) record(delivery *entity.Delivery, code int, sendErr error, now time.Time) {
+	delivery.Attempts++
+	delivery.LastStatusCode = code
+	delivery.LastError = ""
+
+	if sendErr == nil && code >= 200 && code < 300 {
+		delivery.Status = entity.DeliveryDelivered
+		delivery.NextAttempt = now
+		return
+	}
+
+	if sendErr != nil {
+		delivery.LastError = sendErr.Error()
+	} else {
+		delivery.LastError = fmt.Sprintf("unexpected status %d", code)
+	}
+
+	if delivery.Attempts >= u.retry.MaxAttempts {
+		delivery.Status = entity.DeliveryDead
+		delivery.NextAttempt = now
+		return
+	}
+
+	delivery.Status = entity.DeliveryPending
+	delivery.NextAttempt = now.Add(u.retry.Backoff(delivery.Attempts))
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, secretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+func TestUsecase_Record(t *testing.T) {
+	u := NewUsecase(nil, nil, entity.RetryPolicy{MaxAttempts: 2, BackoffBase: time.Minute, BackoffMax: time.Hour}, time.Minute)
+	now := time.Now()
+
+	delivery := &entity.Delivery{Status: entity.DeliveryPending}
+	u.record(delivery, 503, nil, now)
+	if delivery.Status != entity.DeliveryPending || !delivery.NextAttempt.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected a retry in a minute, got %s at %v", delivery.Status, delivery.NextAttempt)
+	}
+	if delivery.LastError == "" {
+		t.Error("Expected the status to be kept as the error")
+	}
+
+	u.record(delivery, 0, errors.New("connection refused"), now)
+	if delivery.Status != entity.DeliveryDead || delivery.Attempts != 2 {
+		t.Errorf("Expected dead after 2 attempts, got %s after %d", delivery.Status, delivery.Attempts)
+	}
+
+	delivery = &entity.Delivery{Status: entity.DeliveryPending}
+	u.record(delivery, 204, nil, now)
+	if delivery.Status != entity.DeliveryDelivered || delivery.LastError != "" {
+		t.Errorf("Expected delivered, got %s (%s)", delivery.Status, delivery.LastError)
+	}
+}
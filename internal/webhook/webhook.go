@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/entity"
+)
+
+type Usecase interface {
+	GetWebhooks(ctx context.Context, tenantId, limit, offset int) ([]entity.Webhook, error)
+	// CreateWebhook returns the webhook with its generated secret
+	CreateWebhook(ctx context.Context, tenantId int, url string) (*entity.Webhook, error)
+	DeleteWebhook(ctx context.Context, tenantId, webhookId int) error
+	GetDeliveries(ctx context.Context, tenantId, webhookId int, status string, limit, offset int) ([]entity.Delivery, error)
+	RetryDelivery(ctx context.Context, tenantId int, deliveryId int64) error
+	// ProcessDeliveries serves the deliveries of all tenants and returns how many were attempted
+	ProcessDeliveries(ctx context.Context, batchSize int) (int, error)
+}
+
+type Repository interface {
+	GetWebhooks(ctx context.Context, tenantId, limit, offset int) ([]entity.Webhook, error)
+	CheckIfWebhookExist(ctx context.Context, tenantId, webhookId int) (bool, error)
+	CreateWebhook(ctx context.Context, tenantId int, webhook *entity.Webhook) (int, error)
+	DeleteWebhook(ctx context.Context, tenantId, webhookId int) error
+	GetDeliveries(ctx context.Context, tenantId, webhookId int, status string, limit, offset int) ([]entity.Delivery, error)
+	// RetryDelivery gives ErrorsNotFound unless the delivery is dead
+	RetryDelivery(ctx context.Context, tenantId int, deliveryId int64) error
+	// FanOut turns up to batchSize outbox events into deliveries to the webhooks of their tenants
+	FanOut(ctx context.Context, batchSize int) (int, error)
+	// ClaimDeliveries takes due deliveries off the queue for lease, so other
+	// replicas don't send them meanwhile
+	ClaimDeliveries(ctx context.Context, batchSize int, lease time.Duration) ([]entity.Delivery, error)
+	SaveAttempt(ctx context.Context, delivery *entity.Delivery) error
+}
+
+// Sender posts a claimed delivery to its webhook and returns the response status.
+type Sender interface {
+	Send(ctx context.Context, delivery *entity.Delivery) (int, error)
+	// CheckURL gives WebhookURLError for endpoints Send refuses to call
+	CheckURL(url string) error
+}
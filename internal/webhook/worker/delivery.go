@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/internal/webhook"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+)
+
+// DeliveryWorker sends the webhook deliveries in the background.
+type DeliveryWorker struct {
+	usecase   webhook.Usecase
+	log       logger.Logger
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      chan struct{}
+	// ctx is cancelled when Close runs out of time, aborting the requests in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewDeliveryWorker(usecase webhook.Usecase, log logger.Logger, interval time.Duration, batchSize int) *DeliveryWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DeliveryWorker{
+		usecase:   usecase,
+		log:       log,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+func (w *DeliveryWorker) Run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.drain()
+		}
+	}
+}
+
+// drain sends batches until fewer than a full batch were due or the worker is stopped.
+func (w *DeliveryWorker) drain() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		processed, err := w.usecase.ProcessDeliveries(w.ctx, w.batchSize)
+		if err != nil {
+			w.log.Error(err.Error())
+			return
+		}
+
+		if processed < w.batchSize {
+			return
+		}
+	}
+}
+
+func (w *DeliveryWorker) Close(ctx context.Context) error {
+	close(w.stop)
+
+	select {
+	case <-w.done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
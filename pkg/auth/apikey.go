@@ -0,0 +1,11 @@
+package auth
+
+import "errors"
+
+var ErrKeyInvalid = errors.New("invalid api key")
+
+// KeyAuthenticator resolves an X-API-Key value to the principal it was issued for.
+// Unknown, revoked and expired keys are reported as ErrKeyInvalid.
+type KeyAuthenticator interface {
+	Authenticate(key string) (*Principal, error)
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+var (
+	ErrTokenMissing   = errors.New("missing token")
+	ErrTokenMalformed = errors.New("token malformed")
+	ErrTokenExpired   = errors.New("token expired")
+	ErrTokenSignature = errors.New("invalid token signature")
+	ErrTokenInvalid   = errors.New("invalid token")
+	ErrTokenTenant    = errors.New("token has no tenant")
+)
+
+// placeholderSecrets are values shipped in examples, a deployment signing with
+// one of them accepts tokens anyone can mint.
+var placeholderSecrets = map[string]bool{
+	"change-me":   true,
+	"changeme":    true,
+	"secret":      true,
+	"jwt-secret":  true,
+	"your-secret": true,
+}
+
+type Claims struct {
+	Role     string `json:"role"`
+	TenantId int    `json:"tenant_id"`
+	jwt.RegisteredClaims
+}
+
+type Verifier struct {
+	algorithm string
+	key       interface{}
+}
+
+// NewVerifier builds a verifier for the configured algorithm. HS256 uses the
+// shared secret, which can be neither empty nor a known placeholder, RS256
+// reads a PEM encoded public key from publicKeyPath.
+func NewVerifier(algorithm, secret, publicKeyPath string) (*Verifier, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		if strings.TrimSpace(secret) == "" {
+			return nil, fmt.Errorf("auth - NewVerifier: empty secret for %s", algorithm)
+		}
+		if placeholderSecrets[strings.ToLower(strings.TrimSpace(secret))] {
+			return nil, fmt.Errorf("auth - NewVerifier: placeholder secret for %s, set JWT_SECRET", algorithm)
+		}
+		return &Verifier{algorithm: algorithm, key: []byte(secret)}, nil
+	case AlgorithmRS256:
+		pem, err := os.ReadFile(publicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("auth - NewVerifier - os.ReadFile: %w", err)
+		}
+
+		key, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("auth - NewVerifier - jwt.ParseRSAPublicKeyFromPEM: %w", err)
+		}
+		return &Verifier{algorithm: algorithm, key: key}, nil
+	default:
+		return nil, fmt.Errorf("auth - NewVerifier: unsupported algorithm %q", algorithm)
+	}
+}
+
+// Verify checks the signature and expiry of the token and returns its claims.
+// A token without a positive tenant_id is rejected, it would otherwise reach
+// data of no tenant in particular. Errors are one of the ErrToken* values so
+// callers can report them apart.
+func (v *Verifier) Verify(tokenString string) (*Claims, error) {
+	if tokenString == "" {
+		return nil, ErrTokenMissing
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+		return v.key, nil
+	}, jwt.WithValidMethods([]string{v.algorithm}), jwt.WithExpirationRequired())
+
+	switch {
+	case err == nil:
+		if claims.TenantId <= 0 {
+			return nil, ErrTokenTenant
+		}
+		return claims, nil
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return nil, ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return nil, ErrTokenSignature
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const secret = "test-secret"
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, expiresAt time.Time) string {
+	t.Helper()
+	return signTenant(t, method, key, expiresAt, 1)
+}
+
+func signTenant(t *testing.T, method jwt.SigningMethod, key interface{}, expiresAt time.Time, tenantId int) string {
+	t.Helper()
+
+	claims := Claims{
+		Role:     "admin",
+		TenantId: tenantId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal("Failed to sign token:", err)
+	}
+	return token
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	v, err := NewVerifier(AlgorithmHS256, secret, "")
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	t.Run("Valid token", func(t *testing.T) {
+		claims, err := v.Verify(sign(t, jwt.SigningMethodHS256, []byte(secret), time.Now().Add(time.Hour)))
+		if err != nil {
+			t.Fatal("Expected nil error, got", err)
+		}
+		if claims.Subject != "alice" || claims.Role != "admin" || claims.TenantId != 1 {
+			t.Errorf("Unexpected claims: %+v", claims)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"Missing token", "", ErrTokenMissing},
+		{"Malformed token", "not-a-jwt", ErrTokenMalformed},
+		{"Expired token", sign(t, jwt.SigningMethodHS256, []byte(secret), time.Now().Add(-time.Hour)), ErrTokenExpired},
+		{"Wrong signature", sign(t, jwt.SigningMethodHS256, []byte("other"), time.Now().Add(time.Hour)), ErrTokenSignature},
+		{"Missing tenant", signTenant(t, jwt.SigningMethodHS256, []byte(secret), time.Now().Add(time.Hour), 0), ErrTokenTenant},
+		{"Negative tenant", signTenant(t, jwt.SigningMethodHS256, []byte(secret), time.Now().Add(time.Hour), -1), ErrTokenTenant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(tt.token); !errors.Is(err, tt.err) {
+				t.Errorf("Expected error '%v', got '%v'", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestNewVerifier_RejectsWeakSecret(t *testing.T) {
+	for _, secret := range []string{"", "  ", "change-me", "CHANGEME", "secret"} {
+		if _, err := NewVerifier(AlgorithmHS256, secret, ""); err == nil {
+			t.Errorf("Expected an error for secret %q", secret)
+		}
+	}
+}
+
+func TestVerifier_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewVerifier(AlgorithmRS256, "", path)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	if _, err := v.Verify(sign(t, jwt.SigningMethodRS256, key, time.Now().Add(time.Hour))); err != nil {
+		t.Error("Expected nil error, got", err)
+	}
+
+	hsToken := sign(t, jwt.SigningMethodHS256, []byte(secret), time.Now().Add(time.Hour))
+	if _, err := v.Verify(hsToken); !errors.Is(err, ErrTokenSignature) {
+		t.Errorf("Expected error '%v', got '%v'", ErrTokenSignature, err)
+	}
+}
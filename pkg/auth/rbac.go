@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+)
+
+type Permission string
+
+const (
+	PermBannerRead         Permission = "banner:read"
+	PermBannerReadInactive Permission = "banner:read_inactive"
+	PermBannerWrite        Permission = "banner:write"
+	PermBannerPublish      Permission = "banner:publish"
+	PermBannerDelete       Permission = "banner:delete"
+	PermTagWrite           Permission = "tag:write"
+	PermFeatureWrite       Permission = "feature:write"
+	PermAPIKeyManage       Permission = "apikey:manage"
+	PermExperimentManage   Permission = "experiment:manage"
+	PermStatsRead          Permission = "stats:read"
+	PermWebhookManage      Permission = "webhook:manage"
+)
+
+var knownPermissions = map[Permission]struct{}{
+	PermBannerRead:         {},
+	PermBannerReadInactive: {},
+	PermBannerWrite:        {},
+	PermBannerPublish:      {},
+	PermBannerDelete:       {},
+	PermTagWrite:           {},
+	PermFeatureWrite:       {},
+	PermAPIKeyManage:       {},
+	PermExperimentManage:   {},
+	PermStatsRead:          {},
+	PermWebhookManage:      {},
+}
+
+func IsKnownPermission(perm Permission) bool {
+	_, ok := knownPermissions[perm]
+	return ok
+}
+
+// Principal is the authenticated caller together with everything it is allowed to do.
+// TenantId scopes every piece of data the caller can reach. KeyId is set for
+// callers using an API key, the names of keys are not unique.
+type Principal struct {
+	Subject     string
+	Role        string
+	TenantId    int
+	KeyId       int
+	Permissions map[Permission]struct{}
+}
+
+func (p *Principal) Can(perm Permission) bool {
+	_, ok := p.Permissions[perm]
+	return ok
+}
+
+// Policy maps role names to the permissions granted by them.
+type Policy map[string]map[Permission]struct{}
+
+func NewPolicy(roles map[string][]string) (Policy, error) {
+	policy := make(Policy, len(roles))
+	for role, perms := range roles {
+		policy[role] = make(map[Permission]struct{}, len(perms))
+		for _, perm := range perms {
+			if !IsKnownPermission(Permission(perm)) {
+				return nil, fmt.Errorf("auth - NewPolicy: unknown permission %q for role %q", perm, role)
+			}
+			policy[role][Permission(perm)] = struct{}{}
+		}
+	}
+	return policy, nil
+}
+
+// Principal resolves the claims of a verified token. Unknown roles get no permissions.
+func (p Policy) Principal(claims *Claims) *Principal {
+	return &Principal{
+		Subject:     claims.Subject,
+		Role:        claims.Role,
+		TenantId:    claims.TenantId,
+		Permissions: p[claims.Role],
+	}
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
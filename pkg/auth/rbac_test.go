@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestPolicy_Principal(t *testing.T) {
+	policy, err := NewPolicy(map[string][]string{
+		"editor":    {"banner:read", "banner:write"},
+		"publisher": {"banner:read", "banner:write", "banner:publish"},
+	})
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	editor := policy.Principal(&Claims{Role: "editor", TenantId: 7, RegisteredClaims: jwt.RegisteredClaims{Subject: "bob"}})
+	if !editor.Can(PermBannerWrite) || editor.Can(PermBannerPublish) {
+		t.Errorf("Unexpected editor permissions: %v", editor.Permissions)
+	}
+	if editor.TenantId != 7 {
+		t.Errorf("Expected tenant 7, got %d", editor.TenantId)
+	}
+
+	publisher := policy.Principal(&Claims{Role: "publisher"})
+	if !publisher.Can(PermBannerPublish) {
+		t.Errorf("Expected publisher to have %s", PermBannerPublish)
+	}
+
+	unknown := policy.Principal(&Claims{Role: "guest"})
+	if unknown.Can(PermBannerRead) {
+		t.Error("Expected unknown role to have no permissions")
+	}
+}
+
+func TestNewPolicy_UnknownPermission(t *testing.T) {
+	if _, err := NewPolicy(map[string][]string{"admin": {"banner:everything"}}); err == nil {
+		t.Error("Expected error for unknown permission")
+	}
+}
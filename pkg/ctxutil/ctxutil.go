@@ -0,0 +1,26 @@
+// Package ctxutil bounds calls to postgres and redis by a per-call timeout.
+package ctxutil
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeout limits a single query or cache call on top of the caller's
+// context. Without a timeout only the caller's deadline applies, the returned
+// context still has to be cancelled.
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Err prefers the context error over err, go-redis reports an expired deadline
+// as a plain network timeout.
+func Err(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package ctxutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), 0)
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Expected no deadline without a timeout")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Error("Expected the context to be cancelled")
+	}
+
+	ctx, cancel = WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("Expected a deadline")
+	}
+}
+
+func TestErr(t *testing.T) {
+	netErr := errors.New("i/o timeout")
+
+	if err := Err(context.Background(), netErr); err != netErr {
+		t.Errorf("Expected the call error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	if err := Err(ctx, netErr); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline error, got %v", err)
+	}
+}
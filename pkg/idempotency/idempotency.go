@@ -0,0 +1,116 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ctxutil"
+	"github.com/go-redis/redis/v8"
+)
+
+const keyPrefix = "idempotency:"
+
+// ErrMismatch is returned when a key is reused with another request body.
+var ErrMismatch = errors.New("idempotency key was used with a different request")
+
+// Record is what is kept under an idempotency key. Status is zero while the
+// first request is still running.
+type Record struct {
+	Hash        string `json:"hash"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+func (r *Record) Done() bool {
+	return r.Status != 0
+}
+
+type Store struct {
+	db      *redis.Client
+	timeout time.Duration
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// NewStore keeps responses in redis for ttl. A key stays reserved for lockTTL
+// when the request that took it never finishes, timeout bounds every call.
+func NewStore(db *redis.Client, timeout, ttl, lockTTL time.Duration) *Store {
+	return &Store{
+		db:      db,
+		timeout: timeout,
+		ttl:     ttl,
+		lockTTL: lockTTL,
+	}
+}
+
+// Reserve takes key for a request with the body hash. A nil record means the
+// caller got the key and has to Save or Release it, otherwise the record of
+// the earlier request is returned, ErrMismatch if its body differs.
+func (s *Store) Reserve(ctx context.Context, key, hash string) (*Record, error) {
+	ctx, cancel := ctxutil.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	reserved, err := json.Marshal(Record{Hash: hash})
+	if err != nil {
+		return nil, err
+	}
+
+	// the earlier record may expire between SETNX and GET, then the key is free again
+	for i := 0; i < 2; i++ {
+		ok, err := s.db.SetNX(ctx, keyPrefix+key, reserved, s.lockTTL).Result()
+		if err != nil {
+			return nil, fmt.Errorf("idempotency: %w", err)
+		}
+		if ok {
+			return nil, nil
+		}
+
+		raw, err := s.db.Get(ctx, keyPrefix+key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("idempotency: %w", err)
+		}
+
+		var record Record
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return nil, fmt.Errorf("idempotency: %w", err)
+		}
+		if record.Hash != hash {
+			return &record, ErrMismatch
+		}
+		return &record, nil
+	}
+	return nil, fmt.Errorf("idempotency: key %q keeps changing", key)
+}
+
+// Save stores the response of the request that reserved key.
+func (s *Store) Save(ctx context.Context, key string, record Record) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Set(ctx, keyPrefix+key, raw, s.ttl).Err(); err != nil {
+		return fmt.Errorf("idempotency: %w", err)
+	}
+	return nil
+}
+
+// Release frees key so that the request can be retried with it.
+func (s *Store) Release(ctx context.Context, key string) error {
+	ctx, cancel := ctxutil.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := s.db.Del(ctx, keyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("idempotency: %w", err)
+	}
+	return nil
+}
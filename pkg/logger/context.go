@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+type entryKey struct{}
+
+// requestEntry is shared by pointer, so fields added deeper in the middleware
+// chain (e.g. the caller after authentication) also reach the access log
+// written on the way out.
+type requestEntry struct {
+	mu    sync.Mutex
+	entry *logrus.Entry
+}
+
+// ContextWithEntry stores the request-scoped entry in ctx.
+func ContextWithEntry(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, &requestEntry{entry: entry})
+}
+
+// AddFields extends the request-scoped entry of ctx, it is a no-op when there is none.
+func AddFields(ctx context.Context, fields logrus.Fields) {
+	re, ok := ctx.Value(entryKey{}).(*requestEntry)
+	if !ok {
+		return
+	}
+
+	re.mu.Lock()
+	re.entry = re.entry.WithFields(fields)
+	re.mu.Unlock()
+}
+
+// FromContext returns the request-scoped entry of ctx, or a plain entry of l
+// for code running outside of a request.
+func (l Logger) FromContext(ctx context.Context) *logrus.Entry {
+	re, ok := ctx.Value(entryKey{}).(*requestEntry)
+	if !ok {
+		return l.WithContext(ctx)
+	}
+
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	return re.entry.WithContext(ctx)
+}
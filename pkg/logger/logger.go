@@ -15,6 +15,7 @@ func NewLogger(logFilePath string) (*Logger, error) {
 	l := logrus.New()
 	l.SetReportCaller(true)
 	l.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339})
+	l.AddHook(traceHook{})
 
 	consoleHandler := logrus.New()
 	consoleHandler.SetOutput(os.Stdout)
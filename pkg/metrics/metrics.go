@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "banner_api"
+
+// Cache operations and their results, the hit ratio is hit / (hit + miss) of "get".
+const (
+	CacheGet = "get"
+	CacheSet = "set"
+	// CacheDelete evicts the entries of a banner that was changed or deleted
+	CacheDelete = "delete"
+
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheOK    = "ok"
+	CacheError = "error"
+)
+
+// Results of recorded impressions: stored in postgres, dropped because the
+// buffer was full, or lost with a batch postgres refused.
+const (
+	ImpressionStored  = "stored"
+	ImpressionDropped = "dropped"
+	ImpressionFailed  = "failed"
+)
+
+// Metrics owns the registry served on the admin port. It is safe for concurrent use.
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	cache    *prometheus.CounterVec
+	// impressions counts what the impression recorder did with the events
+	impressions *prometheus.CounterVec
+}
+
+// New creates the registry with the http and cache metrics and the Go runtime
+// and process collectors already registered.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of handled HTTP requests.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of handled HTTP requests.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"route", "method", "status"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_requests_total",
+			Help:      "Number of banner cache calls by operation and result.",
+		}, []string{"operation", "result"}),
+		impressions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "impressions_total",
+			Help:      "Number of recorded banner impressions by result.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.cache,
+		m.impressions,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Register adds collectors of other components, e.g. the postgres pool stats.
+func (m *Metrics) Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := m.registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a handled request. route must be the route template,
+// not the raw path, to keep the label cardinality bounded.
+func (m *Metrics) ObserveRequest(route, method string, status int, elapsed time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.duration.WithLabelValues(route, method, code).Observe(elapsed.Seconds())
+}
+
+// ObserveCache records the result of a cache call.
+func (m *Metrics) ObserveCache(operation, result string) {
+	m.cache.WithLabelValues(operation, result).Inc()
+}
+
+// ObserveImpressions records n impressions with the same result.
+func (m *Metrics) ObserveImpressions(result string, n int) {
+	m.impressions.WithLabelValues(result).Add(float64(n))
+}
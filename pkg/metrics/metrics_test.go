@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics_ObserveCache(t *testing.T) {
+	m := New()
+	m.ObserveCache(CacheGet, CacheHit)
+	m.ObserveCache(CacheGet, CacheHit)
+	m.ObserveCache(CacheGet, CacheMiss)
+
+	if got := testutil.ToFloat64(m.cache.WithLabelValues(CacheGet, CacheHit)); got != 2 {
+		t.Errorf("Expected 2 hits, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.cache.WithLabelValues(CacheGet, CacheMiss)); got != 1 {
+		t.Errorf("Expected 1 miss, got %v", got)
+	}
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.ObserveRequest("/api/v1/user_banner", http.MethodGet, http.StatusOK, 3*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	for _, want := range []string{
+		`banner_api_http_requests_total{method="GET",route="/api/v1/user_banner",status="200"} 1`,
+		`banner_api_http_request_duration_seconds_bucket{method="GET",route="/api/v1/user_banner",status="200",le="0.005"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected %q in the exposition", want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// AccessLog writes one entry per request. It must run after RequestID to pick up
+// the request id and, through logger.AddFields, the caller set by Auth.
+func AccessLog(log *logger.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := newStatusRecorder(w)
+
+			next.ServeHTTP(rec, r)
+
+			log.FromContext(r.Context()).WithFields(logrus.Fields{
+				"method":     r.Method,
+				"route":      routeTemplate(r),
+				"status":     rec.status,
+				"bytes":      rec.bytes,
+				"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			}).Info("access")
+		})
+	}
+}
+
+// routeTemplate returns the matched route template, so /banner/1 and /banner/2 look the same.
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tpl, err := current.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unknown"
+}
@@ -1,27 +1,94 @@
 package middleware
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
-func Auth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := r.Header.Get("token")
-		if token == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		if token != "admin" && token != "user" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		if token == "user" && (r.Method != http.MethodGet) {
-			http.Error(w, "Forbidden", http.StatusForbidden)
-			return
-		}
-
-		next.ServeHTTP(w, r)
+const apiKeyHeader = "X-API-Key"
+
+// Auth authenticates the caller either by X-API-Key or by a JWT and stores the
+// resulting principal in the request context.
+func Auth(verifier *auth.Verifier, policy auth.Policy, keys auth.KeyAuthenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get(apiKeyHeader); key != "" {
+				principal, err := keys.Authenticate(key)
+				if err != nil {
+					if errors.Is(err, auth.ErrKeyInvalid) {
+						writeError(w, http.StatusUnauthorized, auth.ErrKeyInvalid.Error())
+						return
+					}
+					writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+					return
+				}
+
+				logCaller(r, principal)
+				next.ServeHTTP(w, r.WithContext(auth.ContextWithPrincipal(r.Context(), principal)))
+				return
+			}
+
+			claims, err := verifier.Verify(bearerToken(r))
+			if err != nil {
+				msg := auth.ErrTokenInvalid.Error()
+				for _, known := range []error{auth.ErrTokenMissing, auth.ErrTokenMalformed, auth.ErrTokenExpired, auth.ErrTokenSignature, auth.ErrTokenTenant} {
+					if errors.Is(err, known) {
+						msg = known.Error()
+						break
+					}
+				}
+				writeError(w, http.StatusUnauthorized, msg)
+				return
+			}
+
+			principal := policy.Principal(claims)
+			logCaller(r, principal)
+			next.ServeHTTP(w, r.WithContext(auth.ContextWithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequirePermission rejects callers whose principal lacks perm. It must run after Auth.
+func RequirePermission(perm auth.Permission) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok || !principal.Can(perm) {
+				writeError(w, http.StatusForbidden, "missing permission "+string(perm))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// logCaller attaches the caller identity to the request-scoped logger.
+func logCaller(r *http.Request, principal *auth.Principal) {
+	logger.AddFields(r.Context(), logrus.Fields{
+		"caller":    principal.Subject,
+		"tenant_id": principal.TenantId,
 	})
 }
+
+// bearerToken reads the JWT from the Authorization header, the legacy
+// "token" header is still accepted for older clients.
+func bearerToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	return r.Header.Get("token")
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
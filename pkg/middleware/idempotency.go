@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/idempotency"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	replayedHeader       = "Idempotent-Replayed"
+	maxIdempotencyKeyLen = 255
+	// maxIdempotentBody caps keyed request bodies, they are held in memory and
+	// their responses kept in redis
+	maxIdempotentBody = 1 << 20
+	// statusClientClosedRequest is written by handlers whose caller went away,
+	// the request was rolled back just like on a 5xx
+	statusClientClosedRequest = 499
+)
+
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, key, hash string) (*idempotency.Record, error)
+	Save(ctx context.Context, key string, record idempotency.Record) error
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotency makes a request with an Idempotency-Key header run at most once:
+// a retry with the same key and body gets the stored response, a retry with
+// another body gets 422 and one arriving while the first still runs gets 409.
+// Keys are scoped to the caller and the route. 5xx responses are not stored,
+// the request can be retried with the same key. Keyed bodies over
+// maxIdempotentBody are rejected with 413.
+func Idempotency(store IdempotencyStore, logger *logger.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			idempotencyKey := req.Header.Get(idempotencyKeyHeader)
+			if idempotencyKey == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			if len(idempotencyKey) > maxIdempotencyKeyLen {
+				writeError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxIdempotentBody))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is larger than %d bytes", maxIdempotentBody))
+					return
+				}
+				writeError(w, http.StatusBadRequest, "invalid body in request")
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			key := req.Method + " " + routeTemplate(req) + ":" + callerKey(req) + ":" + idempotencyKey
+			hash := bodyHash(body)
+			record, err := store.Reserve(req.Context(), key, hash)
+			switch {
+			case errors.Is(err, idempotency.ErrMismatch):
+				writeError(w, http.StatusUnprocessableEntity, err.Error())
+				return
+			case err != nil:
+				logger.FromContext(req.Context()).Errorf("idempotency: %v", err)
+				writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+				return
+			case record != nil && !record.Done():
+				writeError(w, http.StatusConflict, "a request with this Idempotency-Key is in progress")
+				return
+			case record != nil:
+				if record.ContentType != "" {
+					w.Header().Set("Content-Type", record.ContentType)
+				}
+				w.Header().Set(replayedHeader, "true")
+				w.WriteHeader(record.Status)
+				_, _ = w.Write(record.Body)
+				return
+			}
+
+			rec := &bodyRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, req)
+
+			// the response is out, the request context may already be gone
+			ctx := context.WithoutCancel(req.Context())
+			if rec.status >= http.StatusInternalServerError || rec.status == statusClientClosedRequest {
+				if err := store.Release(ctx, key); err != nil {
+					logger.FromContext(ctx).Errorf("idempotency: %v", err)
+				}
+				return
+			}
+
+			err = store.Save(ctx, key, idempotency.Record{
+				Hash:        hash,
+				Status:      rec.status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			})
+			if err != nil {
+				logger.FromContext(ctx).Errorf("idempotency: %v", err)
+			}
+		})
+	}
+}
+
+// bodyHash identifies the request body. JSON bodies are hashed in a canonical
+// form so that a retry serialized with other spacing or key order matches.
+func bodyHash(body []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err == nil {
+		if canonical, err := json.Marshal(value); err == nil {
+			body = canonical
+		}
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// bodyRecorder keeps a copy of the response for the idempotency store.
+type bodyRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *bodyRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
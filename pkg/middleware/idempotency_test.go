@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/idempotency"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type memoryStore struct {
+	records map[string]idempotency.Record
+}
+
+func (s *memoryStore) Reserve(ctx context.Context, key, hash string) (*idempotency.Record, error) {
+	if record, ok := s.records[key]; ok {
+		if record.Hash != hash {
+			return &record, idempotency.ErrMismatch
+		}
+		return &record, nil
+	}
+	s.records[key] = idempotency.Record{Hash: hash}
+	return nil, nil
+}
+
+func (s *memoryStore) Save(ctx context.Context, key string, record idempotency.Record) error {
+	s.records[key] = record
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, key string) error {
+	delete(s.records, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	store := &memoryStore{records: map[string]idempotency.Record{}}
+
+	calls, status := 0, http.StatusCreated
+	r := mux.NewRouter()
+	r.Use(Idempotency(store, &logger.Logger{Logger: l}))
+	r.HandleFunc("/banner", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, calls)
+	})
+
+	doAs := func(principal *auth.Principal, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/banner", strings.NewReader(body))
+		if principal != nil {
+			req = req.WithContext(auth.ContextWithPrincipal(req.Context(), principal))
+		}
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	do := func(key, body string) *httptest.ResponseRecorder {
+		return doAs(nil, key, body)
+	}
+
+	t.Run("Replays the stored response", func(t *testing.T) {
+		first := do("a", `{"feature_id": 1, "tag_ids": [1, 2]}`)
+		replay := do("a", `{"tag_ids":[1,2],"feature_id":1}`)
+
+		if calls != 1 {
+			t.Errorf("Expected a single call, got %d", calls)
+		}
+		if replay.Code != first.Code || replay.Body.String() != first.Body.String() {
+			t.Errorf("Expected %d %q, got %d %q", first.Code, first.Body, replay.Code, replay.Body)
+		}
+		if replay.Header().Get(replayedHeader) != "true" || replay.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected replayed headers, got %v", replay.Header())
+		}
+	})
+
+	t.Run("Rejects another body", func(t *testing.T) {
+		if rec := do("a", `{"feature_id": 2}`); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected 422, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Rejects a request in progress", func(t *testing.T) {
+		store.records["POST /banner:ip:192.0.2.1:b"] = idempotency.Record{Hash: bodyHash([]byte(`{}`))}
+		if rec := do("b", `{}`); rec.Code != http.StatusConflict {
+			t.Errorf("Expected 409, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Frees the key after a server error", func(t *testing.T) {
+		calls, status = 0, http.StatusInternalServerError
+		do("c", `{}`)
+		status = http.StatusCreated
+		if rec := do("c", `{}`); rec.Code != http.StatusCreated || calls != 2 {
+			t.Errorf("Expected the retry to run, got %d after %d calls", rec.Code, calls)
+		}
+	})
+
+	t.Run("Rejects a body over the cap", func(t *testing.T) {
+		calls = 0
+		body := `{"content": "` + strings.Repeat("a", maxIdempotentBody) + `"}`
+		if rec := do("d", body); rec.Code != http.StatusRequestEntityTooLarge || calls != 0 {
+			t.Errorf("Expected 413 without a call, got %d after %d calls", rec.Code, calls)
+		}
+		if _, ok := store.records["POST /banner:ip:192.0.2.1:d"]; ok {
+			t.Error("Expected the key not to be reserved")
+		}
+	})
+
+	t.Run("Scopes keys by API key", func(t *testing.T) {
+		calls = 0
+		// two API keys of the tenant with the same name
+		first := doAs(&auth.Principal{Subject: "apikey:app", TenantId: 3, KeyId: 1}, "e", `{}`)
+		second := doAs(&auth.Principal{Subject: "apikey:app", TenantId: 3, KeyId: 2}, "e", `{}`)
+		if calls != 2 || second.Header().Get(replayedHeader) != "" {
+			t.Errorf("Expected a call per key, got %d with %q and %q", calls, first.Body, second.Body)
+		}
+	})
+
+	t.Run("Passes requests without a key", func(t *testing.T) {
+		calls = 0
+		do("", `{}`)
+		do("", `{}`)
+		if calls != 2 {
+			t.Errorf("Expected 2 calls, got %d", calls)
+		}
+	})
+}
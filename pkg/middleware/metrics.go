@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/metrics"
+	"github.com/gorilla/mux"
+)
+
+// Metrics records count and latency of every request labelled by the matched
+// route template, so /banner/1 and /banner/2 share a series.
+func Metrics(m *metrics.Metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			rec := newStatusRecorder(w)
+
+			next.ServeHTTP(rec, req)
+
+			m.ObserveRequest(routeTemplate(req), req.Method, rec.status, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ratelimit"
+	"github.com/gorilla/mux"
+)
+
+const (
+	rateLimitHeader     = "X-RateLimit-Limit"
+	rateRemainingHeader = "X-RateLimit-Remaining"
+	rateResetHeader     = "X-RateLimit-Reset"
+	retryAfterHeader    = "Retry-After"
+)
+
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, quota ratelimit.Quota) (ratelimit.Result, error)
+}
+
+// RateLimit throttles every caller per route with the quota of the route.
+// Behind Auth callers are told apart by their principal, i.e. the API key or
+// the subject of the token, otherwise by the remote IP. When the limiter is
+// unavailable requests are let through, redis must not take the API down.
+func RateLimit(limiter RateLimiter, quotas ratelimit.Quotas, logger *logger.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			route := routeTemplate(req)
+			key := req.Method + " " + route + ":" + callerKey(req)
+			if allow(w, req, limiter, key, quotas.For(req.Method, route), logger) {
+				next.ServeHTTP(w, req)
+			}
+		})
+	}
+}
+
+// RateLimitIP throttles every remote IP with one quota for all routes. It goes
+// in front of Auth, so that requests with unknown credentials are bounded too
+// and can't flood the lookup of API keys.
+func RateLimitIP(limiter RateLimiter, quota ratelimit.Quota, logger *logger.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if allow(w, req, limiter, remoteIPKey(req), quota, logger) {
+				next.ServeHTTP(w, req)
+			}
+		})
+	}
+}
+
+// allow takes a request from the bucket of key and writes the rate limit
+// headers, a throttled request is answered with 429 and false is returned.
+func allow(w http.ResponseWriter, req *http.Request, limiter RateLimiter, key string, quota ratelimit.Quota, logger *logger.Logger) bool {
+	if quota.Unlimited() {
+		return true
+	}
+
+	result, err := limiter.Allow(req.Context(), key, quota)
+	if err != nil {
+		logger.FromContext(req.Context()).Errorf("rate limit: %v", err)
+		return true
+	}
+
+	w.Header().Set(rateLimitHeader, strconv.Itoa(result.Limit))
+	w.Header().Set(rateRemainingHeader, strconv.Itoa(result.Remaining))
+	w.Header().Set(rateResetHeader, strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+	if !result.Allowed {
+		w.Header().Set(retryAfterHeader, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+// callerKey names the caller of the request. Subjects are only unique within
+// a tenant, so the tenant is part of the name. API keys may share a name, they
+// are named by id instead.
+func callerKey(req *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(req.Context()); ok {
+		if principal.KeyId != 0 {
+			return fmt.Sprintf("tenant:%d:apikey:%d", principal.TenantId, principal.KeyId)
+		}
+		return fmt.Sprintf("tenant:%d:%s", principal.TenantId, principal.Subject)
+	}
+	return remoteIPKey(req)
+}
+
+func remoteIPKey(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ceilSeconds rounds up so that clients waiting the advertised time are never
+// throttled again, a limit is never advertised as lifting in 0 seconds.
+func ceilSeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/auth"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ratelimit"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// fakeLimiter lets the first allowed requests of every key through.
+type fakeLimiter struct {
+	allowed int
+	err     error
+	keys    map[string]int
+}
+
+func (f *fakeLimiter) Allow(ctx context.Context, key string, quota ratelimit.Quota) (ratelimit.Result, error) {
+	if f.err != nil {
+		return ratelimit.Result{}, f.err
+	}
+	f.keys[key]++
+	// like the limiter the bucket size is reported
+	limit := quota.Limit
+	if quota.Burst > 0 {
+		limit = quota.Burst
+	}
+	if f.keys[key] > f.allowed {
+		return ratelimit.Result{Limit: limit, RetryAfter: 1500 * time.Millisecond, ResetAfter: 3 * time.Second}, nil
+	}
+	return ratelimit.Result{Allowed: true, Limit: limit, Remaining: f.allowed - f.keys[key], ResetAfter: time.Second}, nil
+}
+
+func newRateLimitRouter(limiter RateLimiter, principal *auth.Principal) *mux.Router {
+	l, _ := test.NewNullLogger()
+	quotas := ratelimit.Quotas{
+		Default: ratelimit.Quota{Limit: 10, Period: time.Second},
+		Routes: map[string]ratelimit.Quota{
+			"GET /user_banner": {Limit: 5, Period: time.Second},
+			"GET /banner":      {Limit: 10, Period: time.Second, Burst: 20},
+			"/tags":            {},
+		},
+	}
+
+	r := mux.NewRouter()
+	if principal != nil {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				next.ServeHTTP(w, req.WithContext(auth.ContextWithPrincipal(req.Context(), principal)))
+			})
+		})
+	}
+	r.Use(RateLimit(limiter, quotas, &logger.Logger{Logger: l}))
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.HandleFunc("/user_banner", ok)
+	r.HandleFunc("/banner", ok)
+	r.HandleFunc("/tags", ok)
+	return r
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Run("Throttles over the quota", func(t *testing.T) {
+		limiter := &fakeLimiter{allowed: 1, keys: map[string]int{}}
+		router := newRateLimitRouter(limiter, nil)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user_banner", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rec.Code)
+		}
+		if got := rec.Header().Get(rateLimitHeader); got != "5" {
+			t.Errorf("Expected route limit 5, got %q", got)
+		}
+		if got := rec.Header().Get(rateRemainingHeader); got != "0" {
+			t.Errorf("Expected 0 remaining, got %q", got)
+		}
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user_banner", nil))
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected 429, got %d", rec.Code)
+		}
+		if got := rec.Header().Get(retryAfterHeader); got != "2" {
+			t.Errorf("Expected Retry-After rounded up to 2, got %q", got)
+		}
+		if got := rec.Header().Get(rateResetHeader); got != "3" {
+			t.Errorf("Expected reset in 3, got %q", got)
+		}
+
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/banner", nil))
+		if rec.Code != http.StatusOK || rec.Header().Get(rateLimitHeader) != "20" {
+			t.Errorf("Expected other route with its burst as the limit, got %d %q", rec.Code, rec.Header().Get(rateLimitHeader))
+		}
+	})
+
+	t.Run("Keys by principal", func(t *testing.T) {
+		limiter := &fakeLimiter{allowed: 1, keys: map[string]int{}}
+		router := newRateLimitRouter(limiter, &auth.Principal{Subject: "admin", TenantId: 3})
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user_banner", nil))
+		if _, ok := limiter.keys["GET /user_banner:tenant:3:admin"]; !ok {
+			t.Errorf("Expected bucket of the principal, got %v", limiter.keys)
+		}
+	})
+
+	t.Run("Keys API keys by id", func(t *testing.T) {
+		limiter := &fakeLimiter{allowed: 1, keys: map[string]int{}}
+		router := newRateLimitRouter(limiter, &auth.Principal{Subject: "apikey:app", TenantId: 3, KeyId: 12})
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user_banner", nil))
+		if _, ok := limiter.keys["GET /user_banner:tenant:3:apikey:12"]; !ok {
+			t.Errorf("Expected bucket of the key, got %v", limiter.keys)
+		}
+	})
+
+	t.Run("Skips unlimited routes", func(t *testing.T) {
+		limiter := &fakeLimiter{allowed: 0, keys: map[string]int{}}
+		router := newRateLimitRouter(limiter, nil)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tags", nil))
+		if rec.Code != http.StatusOK || len(limiter.keys) != 0 {
+			t.Errorf("Expected no limit, got %d with %v", rec.Code, limiter.keys)
+		}
+	})
+
+	t.Run("Lets requests through without redis", func(t *testing.T) {
+		router := newRateLimitRouter(&fakeLimiter{err: errors.New("connection refused")}, nil)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user_banner", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d", rec.Code)
+		}
+	})
+}
+
+func TestRateLimitIP(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	limiter := &fakeLimiter{allowed: 1, keys: map[string]int{}}
+
+	r := mux.NewRouter()
+	r.Use(RateLimitIP(limiter, ratelimit.Quota{Limit: 10, Period: time.Second}, &logger.Logger{Logger: l}))
+	// stands in for Auth rejecting the credentials
+	r.HandleFunc("/banner", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnauthorized) })
+
+	codes := []int{http.StatusUnauthorized, http.StatusTooManyRequests}
+	for _, code := range codes {
+		req := httptest.NewRequest(http.MethodGet, "/banner", nil)
+		req.Header.Set("X-API-Key", "unknown")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != code {
+			t.Errorf("Expected %d, got %d", code, rec.Code)
+		}
+	}
+
+	if _, ok := limiter.keys["ip:192.0.2.1"]; !ok {
+		t.Errorf("Expected bucket of the remote IP, got %v", limiter.keys)
+	}
+}
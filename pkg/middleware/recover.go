@@ -14,7 +14,7 @@ func PanicRecovery(logger *logger.Logger) mux.MiddlewareFunc {
 			defer func() {
 				if err := recover(); err != nil {
 					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-					logger.Error(string(debug.Stack()))
+					logger.FromContext(req.Context()).Error(string(debug.Stack()))
 				}
 			}()
 			next.ServeHTTP(w, req)
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/logger"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func newTestRouter(log *logger.Logger) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(RequestID(log))
+	r.Use(AccessLog(log))
+	r.HandleFunc("/banner/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger.AddFields(r.Context(), logrus.Fields{"caller": "alice"})
+		log.FromContext(r.Context()).Info("handler")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("body"))
+	})
+	return r
+}
+
+func TestRequestID(t *testing.T) {
+	l, hook := test.NewNullLogger()
+	router := newTestRouter(&logger.Logger{Logger: l})
+
+	t.Run("Accepts caller id", func(t *testing.T) {
+		hook.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/banner/1", nil)
+		req.Header.Set(requestIDHeader, "abc-123")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get(requestIDHeader); got != "abc-123" {
+			t.Errorf("Expected echoed request id, got %q", got)
+		}
+		for _, entry := range hook.AllEntries() {
+			if entry.Data["request_id"] != "abc-123" {
+				t.Errorf("Expected request id on %q, got %v", entry.Message, entry.Data)
+			}
+		}
+	})
+
+	t.Run("Replaces invalid id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/banner/1", nil)
+		req.Header.Set(requestIDHeader, strings.Repeat("x", maxRequestIDLen+1))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get(requestIDHeader); len(got) != 32 {
+			t.Errorf("Expected generated request id, got %q", got)
+		}
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	l, hook := test.NewNullLogger()
+	router := newTestRouter(&logger.Logger{Logger: l})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/banner/42", nil))
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Message != "access" {
+		t.Fatalf("Expected access log entry, got %v", entry)
+	}
+
+	want := map[string]interface{}{
+		"method": http.MethodGet,
+		"route":  "/banner/{id}",
+		"status": http.StatusTeapot,
+		"bytes":  4,
+		"caller": "alice",
+	}
+	for key, value := range want {
+		if entry.Data[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, entry.Data[key])
+		}
+	}
+	if _, ok := entry.Data["latency_ms"]; !ok {
+		t.Error("Expected latency_ms field")
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Timeout puts a deadline on the request context. http.Server doesn't cancel
+// handlers when its WriteTimeout fires, so without it queries keep running for
+// a response nobody can receive.
+func Timeout(timeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
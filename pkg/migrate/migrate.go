@@ -0,0 +1,285 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var (
+	ErrSchemaBehind   = errors.New("database schema is behind, run migrate up")
+	ErrNoMigration    = errors.New("no migration to roll back")
+	ErrUnknownVersion = errors.New("applied migration is missing from the binary")
+)
+
+const (
+	// lockKey is an arbitrary application-wide key for pg_advisory_lock, it keeps
+	// replicas started at the same time from applying the same migration twice.
+	lockKey = 7204_1813
+
+	createTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+						  version BIGINT PRIMARY KEY,
+						  name VARCHAR NOT NULL,
+						  applied_at timestamp NOT NULL DEFAULT now()
+					  );`
+
+	appliedSQL = `SELECT version, applied_at FROM schema_migrations ORDER BY version;`
+	insertSQL  = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`
+	deleteSQL  = `DELETE FROM schema_migrations WHERE version = $1;`
+	lockSQL    = `SELECT pg_advisory_lock($1);`
+	unlockSQL  = `SELECT pg_advisory_unlock($1);`
+)
+
+// fileName matches 000001_init.up.sql and 000001_init.down.sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// New reads migrations from the root of fsys. Every version needs both an up and
+// a down file.
+func New(db *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: %w", entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d has two names: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrate: version %d needs both up and down files", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies all pending migrations in order, each in its own transaction, and
+// returns the applied ones.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, migration.Up, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, insertSQL, migration.Version, migration.Name)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migrate: up %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the latest applied migration.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var done *Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		var latest int64 = -1
+		for version := range applied {
+			if version > latest {
+				latest = version
+			}
+		}
+		if latest < 0 {
+			return ErrNoMigration
+		}
+
+		migration, ok := m.find(latest)
+		if !ok {
+			return fmt.Errorf("migrate: version %d: %w", latest, ErrUnknownVersion)
+		}
+
+		if err := m.apply(ctx, conn, migration.Down, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, deleteSQL, migration.Version)
+			return err
+		}); err != nil {
+			return fmt.Errorf("migrate: down %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = &migration
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every known migration, AppliedAt is nil for pending ones.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	defer conn.Release()
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind when some migration is not applied yet. A schema
+// ahead of the binary is accepted, so a rolled back release still starts.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			return fmt.Errorf("migrate: version %d_%s is pending: %w", status.Version, status.Name, ErrSchemaBehind)
+		}
+	}
+	return nil
+}
+
+// locked runs fn on a single connection holding the advisory lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, lockSQL, lockKey); err != nil {
+		return fmt.Errorf("migrate: lock: %w", err)
+	}
+	// the lock belongs to the session, release it even if ctx is already done
+	defer conn.Exec(context.Background(), unlockSQL, lockKey)
+
+	return fn(conn)
+}
+
+// applied creates the bookkeeping table on first use and returns applied versions.
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	if _, err := conn.Exec(ctx, createTableSQL); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, appliedSQL)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// apply runs the migration body and the bookkeeping in one transaction.
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, body string, record func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, body); err != nil {
+		return err
+	}
+
+	if err := record(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
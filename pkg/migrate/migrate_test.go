@@ -0,0 +1,50 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Orders migrations", func(t *testing.T) {
+		m, err := New(nil, fstest.MapFS{
+			"000002_add_index.up.sql":   {Data: []byte("CREATE INDEX;")},
+			"000002_add_index.down.sql": {Data: []byte("DROP INDEX;")},
+			"000001_init.up.sql":        {Data: []byte("CREATE TABLE;")},
+			"000001_init.down.sql":      {Data: []byte("DROP TABLE;")},
+			"README.md":                 {Data: []byte("ignored")},
+		})
+		if err != nil {
+			t.Fatal("Expected nil error, got", err)
+		}
+
+		if len(m.migrations) != 2 {
+			t.Fatalf("Expected 2 migrations, got %d", len(m.migrations))
+		}
+		if first := m.migrations[0]; first.Version != 1 || first.Name != "init" || first.Up != "CREATE TABLE;" || first.Down != "DROP TABLE;" {
+			t.Errorf("Unexpected first migration: %+v", first)
+		}
+		if m.migrations[1].Version != 2 {
+			t.Errorf("Expected version 2 second, got %d", m.migrations[1].Version)
+		}
+	})
+
+	t.Run("Missing down file", func(t *testing.T) {
+		_, err := New(nil, fstest.MapFS{
+			"000001_init.up.sql": {Data: []byte("CREATE TABLE;")},
+		})
+		if err == nil {
+			t.Error("Expected error for migration without down file")
+		}
+	})
+
+	t.Run("Conflicting names", func(t *testing.T) {
+		_, err := New(nil, fstest.MapFS{
+			"000001_init.up.sql":    {Data: []byte("CREATE TABLE;")},
+			"000001_other.down.sql": {Data: []byte("DROP TABLE;")},
+		})
+		if err == nil {
+			t.Error("Expected error for version with two names")
+		}
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/tracing"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
@@ -20,6 +22,9 @@ func New(connStr string, poolSize int32) (*Postgres, error) {
 	}
 
 	poolConfig.MaxConns = poolSize
+	// successful queries are only reported at info level, the tracer needs all of them
+	poolConfig.ConnConfig.Logger = tracing.NewPgxLogger()
+	poolConfig.ConnConfig.LogLevel = pgx.LogLevelInfo
 
 	pg.Pool, err = pgxpool.ConnectConfig(context.Background(), poolConfig)
 
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DmitriyKomarovCoder/banner-api/pkg/ctxutil"
+	"github.com/go-redis/redis/v8"
+)
+
+const keyPrefix = "ratelimit:"
+
+// Quota lets Limit requests through per Period, with bursts of up to Burst
+// requests at once. Burst defaults to Limit, a zero Limit disables the limit.
+type Quota struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+func (q Quota) Unlimited() bool {
+	return q.Limit <= 0 || q.Period <= 0
+}
+
+func (q Quota) burst() int {
+	if q.Burst > 0 {
+		return q.Burst
+	}
+	return q.Limit
+}
+
+// interval is the time it takes to regain a single request.
+func (q Quota) interval() time.Duration {
+	interval := q.Period / time.Duration(q.Limit)
+	if interval < time.Microsecond {
+		return time.Microsecond
+	}
+	return interval
+}
+
+// Quotas picks the quota of a route. Routes are keyed by the route template,
+// optionally prefixed with the method, "GET /api/v1/banner" wins over
+// "/api/v1/banner"; routes without an entry get Default. PerIP bounds every
+// remote address across all routes before the caller is authenticated.
+type Quotas struct {
+	Default Quota
+	Routes  map[string]Quota
+	PerIP   Quota
+}
+
+func (q Quotas) For(method, route string) Quota {
+	if quota, ok := q.Routes[method+" "+route]; ok {
+		return quota
+	}
+	if quota, ok := q.Routes[route]; ok {
+		return quota
+	}
+	return q.Default
+}
+
+// Result of a single request: Limit is the size of the bucket, i.e. the burst,
+// Remaining requests may follow right away and the bucket is full again after
+// ResetAfter. RetryAfter is only set when the request is not Allowed.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// gcraScript is a token bucket in the GCRA form: the key holds the time the
+// bucket becomes full again, all times are in microseconds of the redis clock
+// so that replicas with skewed clocks share the bucket fairly.
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+local burst = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - burst * interval
+if allow_at > now then
+	return {0, 0, allow_at - now, tat - now}
+end
+
+redis.call("SET", KEYS[1], string.format("%d", new_tat), "PX", math.ceil((new_tat - now) / 1000))
+return {1, math.floor((now - allow_at) / interval), 0, new_tat - now}
+`)
+
+type Limiter struct {
+	db      *redis.Client
+	timeout time.Duration
+}
+
+// NewLimiter keeps the buckets in redis so that all replicas share them,
+// timeout bounds every call.
+func NewLimiter(db *redis.Client, timeout time.Duration) *Limiter {
+	return &Limiter{
+		db:      db,
+		timeout: timeout,
+	}
+}
+
+// Allow takes a request from the bucket of key.
+func (l *Limiter) Allow(ctx context.Context, key string, quota Quota) (Result, error) {
+	if quota.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+
+	ctx, cancel := ctxutil.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	burst, interval := quota.burst(), quota.interval()
+	values, err := gcraScript.Run(ctx, l.db, []string{keyPrefix + key}, burst, interval.Microseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: %w", err)
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected reply %v", values)
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		ResetAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
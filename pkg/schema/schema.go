@@ -0,0 +1,94 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// resource is the name the schema is registered under, it only shows up in
+// error messages.
+const resource = "content.json"
+
+var ErrRemoteRef = errors.New("schema: references to other documents are not allowed")
+
+// Violation is one place where a document breaks the schema. Path is a JSON
+// pointer into the document, empty for the document itself.
+type Violation struct {
+	Path    string
+	Message string
+}
+
+type Schema struct {
+	schema *jsonschema.Schema
+}
+
+// Compile parses a draft 2020-12 schema, unless it names another draft in
+// $schema. The schema must be self-contained, $ref may only point inside it.
+func Compile(raw []byte) (*Schema, error) {
+	c := jsonschema.NewCompiler()
+	c.Draft = jsonschema.Draft2020
+	c.LoadURL = func(string) (io.ReadCloser, error) {
+		return nil, ErrRemoteRef
+	}
+
+	if err := c.AddResource(resource, bytes.NewReader(raw)); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+
+	s, err := c.Compile(resource)
+	if err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	return &Schema{schema: s}, nil
+}
+
+// Validate returns the violations of v, which may be any value encoding/json
+// can marshal. No violations and no error means v matches.
+func (s *Schema) Validate(v interface{}) ([]Violation, error) {
+	// the validator wants the output of a json.Decoder with UseNumber, so go
+	// through the encoding instead of guessing what v holds
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+
+	err = s.schema.Validate(doc)
+	if err == nil {
+		return nil, nil
+	}
+
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+
+	violations := leaves(verr, nil)
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	return violations, nil
+}
+
+// leaves keeps only the innermost causes, the outer ones just repeat that some
+// subschema failed.
+func leaves(verr *jsonschema.ValidationError, violations []Violation) []Violation {
+	if len(verr.Causes) == 0 {
+		return append(violations, Violation{Path: verr.InstanceLocation, Message: verr.Message})
+	}
+
+	for _, cause := range verr.Causes {
+		violations = leaves(cause, violations)
+	}
+	return violations
+}
@@ -0,0 +1,58 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+const bannerSchema = `{
+	"type": "object",
+	"required": ["title", "url"],
+	"properties": {
+		"title": {"type": "string", "minLength": 1},
+		"url": {"type": "string"},
+		"priority": {"type": "integer", "maximum": 10}
+	}
+}`
+
+func TestSchema_Validate(t *testing.T) {
+	s, err := Compile([]byte(bannerSchema))
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	violations, err := s.Validate(map[string]interface{}{"title": "sale", "url": "https://example.com", "priority": 3})
+	if err != nil || len(violations) != 0 {
+		t.Fatalf("Expected valid content, got %v %v", violations, err)
+	}
+
+	violations, err = s.Validate(map[string]interface{}{"title": "", "priority": 11.0})
+	if err != nil {
+		t.Fatal("Expected nil error, got", err)
+	}
+
+	paths := map[string]bool{}
+	for _, v := range violations {
+		paths[v.Path] = true
+	}
+	for _, want := range []string{"", "/title", "/priority"} {
+		if !paths[want] {
+			t.Errorf("Expected a violation at %q, got %v", want, violations)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	if _, err := Compile([]byte(`{"type": "object"`)); err == nil {
+		t.Error("Expected error for malformed JSON")
+	}
+
+	if _, err := Compile([]byte(`{"type": "no-such-type"}`)); err == nil {
+		t.Error("Expected error for invalid schema")
+	}
+
+	_, err := Compile([]byte(`{"$ref": "file:///etc/passwd"}`))
+	if !errors.Is(err, ErrRemoteRef) {
+		t.Errorf("Expected ErrRemoteRef, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var pgxTracer = otel.Tracer("github.com/DmitriyKomarovCoder/banner-api/pkg/tracing/pgx")
+
+// pgx v4 has no query hooks, but it logs every finished query together with its
+// duration, which is enough to record the span after the fact.
+var tracedQueries = map[string]struct{}{
+	"Query":             {},
+	"Exec":              {},
+	"SendBatch":         {},
+	"BatchResult.Exec":  {},
+	"BatchResult.Query": {},
+}
+
+type pgxLogger struct{}
+
+// NewPgxLogger returns a pgx logger that turns query log records into spans.
+// It must be installed with pgx.LogLevelInfo, successful queries are logged at that level.
+func NewPgxLogger() pgx.Logger {
+	return pgxLogger{}
+}
+
+func (pgxLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	if _, ok := tracedQueries[msg]; !ok {
+		return
+	}
+
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return
+	}
+
+	end := time.Now()
+	start := end
+	if elapsed, ok := data["time"].(time.Duration); ok {
+		start = end.Add(-elapsed)
+	}
+
+	attrs := []attribute.KeyValue{semconv.DBSystemPostgreSQL}
+	if sql, ok := data["sql"].(string); ok {
+		attrs = append(attrs, semconv.DBQueryText(sql))
+	}
+
+	_, span := pgxTracer.Start(ctx, "pgx."+msg,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(attrs...),
+	)
+
+	if err, ok := data["err"].(error); ok && level == pgx.LogLevelError {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End(trace.WithTimestamp(end))
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestPgxLogger_Log(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer provider.Shutdown(context.Background())
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	logger := NewPgxLogger()
+
+	logger.Log(ctx, pgx.LogLevelInfo, "Query", map[string]interface{}{"sql": "SELECT 1", "time": 20 * time.Millisecond})
+	logger.Log(ctx, pgx.LogLevelError, "Exec", map[string]interface{}{"sql": "DELETE", "err": errors.New("boom")})
+	logger.Log(ctx, pgx.LogLevelInfo, "closed connection", nil)
+	logger.Log(context.Background(), pgx.LogLevelInfo, "Query", map[string]interface{}{"sql": "SELECT 2"})
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 2 query spans and the parent, got %d", len(spans))
+	}
+
+	query := spans[0]
+	if query.Name() != "pgx.Query" || query.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Unexpected query span %s under %s", query.Name(), query.Parent().SpanID())
+	}
+	if d := query.EndTime().Sub(query.StartTime()); d != 20*time.Millisecond {
+		t.Errorf("Expected span to last 20ms, got %v", d)
+	}
+
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("Expected failed exec to have error status, got %v", spans[1].Status())
+	}
+}